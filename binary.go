@@ -0,0 +1,172 @@
+package ihex
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// A BinaryOption configures ToBinary or FromBinary.
+type BinaryOption func(*binaryConfig)
+
+type binaryConfig struct {
+	pad        byte
+	clamp      bool
+	start, end uint32
+	popts      []Option
+	wopts      []WriterOption
+	transform  Transform
+}
+
+// PadByte sets the value that ToBinary uses to fill gaps in the data;
+// the default is 0xFF, the value of erased flash memory.
+func PadByte(b byte) BinaryOption {
+	return func(c *binaryConfig) {
+		c.pad = b
+	}
+}
+
+// Clamp limits a conversion to the addresses in the range [start, end).
+// The binary read or written by ToBinary then covers exactly that
+// range, padded as needed, instead of the range of the data.
+func Clamp(start, end uint32) BinaryOption {
+	return func(c *binaryConfig) {
+		c.clamp = true
+		c.start, c.end = start, end
+	}
+}
+
+// ParserOptions sets the options for the Parser used by ToBinary.
+func ParserOptions(opts ...Option) BinaryOption {
+	return func(c *binaryConfig) {
+		c.popts = opts
+	}
+}
+
+// WriterOptions sets the options for the Writer used by FromBinary.
+func WriterOptions(opts ...WriterOption) BinaryOption {
+	return func(c *binaryConfig) {
+		c.wopts = opts
+	}
+}
+
+// TransformData passes the data through t during a conversion, before
+// any Clamp range is applied.
+func TransformData(t Transform) BinaryOption {
+	return func(c *binaryConfig) {
+		c.transform = t
+	}
+}
+
+func newBinaryConfig(opts []BinaryOption) *binaryConfig {
+	c := &binaryConfig{pad: 0xff}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// crop returns the part of r within the Clamp range, if there is one.
+func (c *binaryConfig) crop(r Record) Record {
+	if !c.clamp {
+		return r
+	}
+	recs, _ := Crop(c.start, c.end).Next(r)
+	if len(recs) == 0 {
+		return Record{}
+	}
+	return recs[0]
+}
+
+// records returns the records that r becomes after any TransformData
+// and Clamp options are applied.
+func (c *binaryConfig) records(r Record) ([]Record, error) {
+	recs := []Record{r}
+	if c.transform != nil {
+		var err error
+		if recs, err = c.transform.Next(r); err != nil {
+			return nil, err
+		}
+	}
+	for i := range recs {
+		recs[i] = c.crop(recs[i])
+	}
+	return recs, nil
+}
+
+// ToBinary converts the Intel HEX file read from r to a flat binary
+// image written to w, which starts at the lowest address of any data
+// and ends after the highest, with any gaps filled.
+func ToBinary(r io.Reader, w io.Writer, opts ...BinaryOption) error {
+	c := newBinaryConfig(opts)
+	if c.clamp && c.end < c.start {
+		return errors.New("clamp range ends before it starts")
+	}
+	p := NewParser(r, c.popts...)
+	img := &Image{}
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		recs, err := c.records(p.Data())
+		if err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			img.segs.add(rec)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+	if !c.clamp {
+		_, err := w.Write(img.Bytes(c.pad))
+		return err
+	}
+	// cover the whole range, even where there is no data
+	b := bytes.Repeat([]byte{c.pad}, int(c.end-c.start))
+	for _, seg := range img.segs {
+		copy(b[seg.Address-c.start:], seg.Bytes)
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// FromBinary converts the flat binary image read from r, which is
+// loaded at baseAddr, to an Intel HEX file written to w.
+func FromBinary(r io.Reader, w io.Writer, baseAddr uint32,
+	opts ...BinaryOption) error {
+	c := newBinaryConfig(opts)
+	hw := NewWriter(w, c.wopts...)
+	buf := make([]byte, 4096)
+	addr := uint64(baseAddr)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if addr+uint64(n) > 1<<32 {
+				return errors.New("binary image extends past the " +
+					"end of the address space")
+			}
+			recs, err := c.records(Record{uint32(addr), buf[:n]})
+			if err != nil {
+				return err
+			}
+			for _, rec := range recs {
+				if len(rec.Bytes) == 0 {
+					continue
+				}
+				if err := hw.WriteData(rec.Address, rec.Bytes); err != nil {
+					return err
+				}
+			}
+			addr += uint64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return hw.Close()
+}
@@ -0,0 +1,74 @@
+package ihex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestToBinary(t *testing.T) {
+	records := `:020002000102F9
+:0100060003F6
+:00000001FF
+`
+	tests := []struct {
+		opts []BinaryOption
+		want []byte
+	}{
+		{nil, []byte{1, 2, 0xff, 0xff, 3}},
+		{[]BinaryOption{PadByte(0), Clamp(0, 8)}, []byte{0, 0, 1, 2, 0, 0, 3, 0}},
+		{[]BinaryOption{Clamp(3, 7)}, []byte{2, 0xff, 0xff, 3}},
+		{[]BinaryOption{TransformData(Offset(-2)), Clamp(0, 3)}, []byte{1, 2, 0xff}},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := ToBinary(strings.NewReader(records), &b, tt.opts...); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if !bytes.Equal(b.Bytes(), tt.want) {
+			t.Errorf("expected % X, got % X", tt.want, b.Bytes())
+		}
+	}
+}
+
+func TestFromBinary(t *testing.T) {
+	bin := []byte{1, 2, 3}
+	tests := []struct {
+		opts []BinaryOption
+		want []Record
+	}{
+		{[]BinaryOption{WriterOptions(RecordLength(2))}, []Record{
+			{0x10000, []byte{1, 2}},
+			{0x10002, []byte{3}},
+		}},
+		{[]BinaryOption{Clamp(0x10001, 0x10003)}, []Record{
+			{0x10001, []byte{2, 3}},
+		}},
+		{[]BinaryOption{TransformData(Chain(Offset(-0x10000), Split(2)))}, []Record{
+			{0, []byte{1, 2}},
+			{2, []byte{3}},
+		}},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		err := FromBinary(bytes.NewReader(bin), &b, 0x10000, tt.opts...)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		recs, err := ParseRecords(b.Bytes())
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		checkRecords(t, recs, tt.want)
+	}
+
+	err := FromBinary(bytes.NewReader(bin), &bytes.Buffer{}, 0xfffffffe)
+	if err == nil {
+		t.Error("expected error for image past the end of the address space")
+	}
+	err = FromBinary(bytes.NewReader(bin), &bytes.Buffer{}, 0,
+		TransformData(Offset(-1)))
+	if err == nil {
+		t.Error("expected error from the transform")
+	}
+}
@@ -0,0 +1,383 @@
+package ihex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The CBOR (RFC 8949) encoding of a record stream is an
+// indefinite-length array of maps, each with an "address" key holding
+// an unsigned integer and a "data" key holding a byte string. Decoders
+// also accept a definite-length array.
+//
+// The encoding of an Image is a map with a "segments" key holding its
+// segments as a definite-length array of records, encoded as in a
+// record stream, and, if the Image has a start address, a "start" key
+// holding a map with an "eip" key, or "cs" and "ip" keys, holding
+// unsigned integers.
+
+const (
+	cborUint   = 0 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborStream = 0x1f // additional information for indefinite length
+	cborBreak  = 0xff
+)
+
+// A CBOREncoder writes data records to an output stream as CBOR.
+type CBOREncoder struct {
+	w       *bufio.Writer
+	started bool
+	err     error
+}
+
+// NewCBOREncoder returns a new CBOREncoder that writes to w.
+func NewCBOREncoder(w io.Writer) *CBOREncoder {
+	return &CBOREncoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes r to the stream.
+func (e *CBOREncoder) Encode(r Record) error {
+	e.start()
+	e.writeHead(cborMap, 2)
+	e.writeText("address")
+	e.writeHead(cborUint, uint64(r.Address))
+	e.writeText("data")
+	e.writeHead(cborBytes, uint64(len(r.Bytes)))
+	e.write(r.Bytes)
+	return e.err
+}
+
+// Close ends the stream and flushes it to the underlying writer. It
+// does not close the underlying writer.
+func (e *CBOREncoder) Close() error {
+	e.start()
+	e.write([]byte{cborBreak})
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *CBOREncoder) start() {
+	if !e.started {
+		e.started = true
+		e.write([]byte{cborArray | cborStream})
+	}
+}
+
+func (e *CBOREncoder) writeText(s string) {
+	e.writeHead(cborText, uint64(len(s)))
+	e.write([]byte(s))
+}
+
+// writeHead writes the initial byte and argument of a data item, using
+// the shortest form for n.
+func (e *CBOREncoder) writeHead(major byte, n uint64) {
+	var b [9]byte
+	switch {
+	case n < 24:
+		b[0] = major | byte(n)
+		e.write(b[:1])
+	case n <= 0xff:
+		b[0], b[1] = major|24, byte(n)
+		e.write(b[:2])
+	case n <= 0xffff:
+		b[0] = major | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		e.write(b[:3])
+	case n <= 0xffffffff:
+		b[0] = major | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		e.write(b[:5])
+	default:
+		b[0] = major | 27
+		binary.BigEndian.PutUint64(b[1:], n)
+		e.write(b[:9])
+	}
+}
+
+func (e *CBOREncoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+// A CBORDecoder reads data records from a CBOR input stream.
+type CBORDecoder struct {
+	r       *bufio.Reader
+	started bool
+	stream  bool
+	left    uint64
+	done    bool
+}
+
+// NewCBORDecoder returns a new CBORDecoder that reads from r.
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	return &CBORDecoder{r: bufio.NewReader(r)}
+}
+
+var errCBORFormat = errors.New("cbor: not a record stream")
+
+// Decode reads the next record from the stream. It returns io.EOF when
+// there are no more records.
+func (d *CBORDecoder) Decode() (Record, error) {
+	if !d.started {
+		d.started = true
+		major, n, stream, err := d.readHead()
+		if err != nil {
+			return Record{}, err
+		}
+		if major != cborArray {
+			return Record{}, errCBORFormat
+		}
+		d.stream, d.left = stream, n
+	}
+	if d.done {
+		return Record{}, io.EOF
+	}
+	if d.stream {
+		b, err := d.r.Peek(1)
+		if err != nil {
+			return Record{}, unexpectedEOF(err)
+		}
+		if b[0] == cborBreak {
+			d.r.Discard(1)
+			d.done = true
+			return Record{}, io.EOF
+		}
+	} else {
+		if d.left == 0 {
+			d.done = true
+			return Record{}, io.EOF
+		}
+		d.left--
+	}
+	return d.readRecord()
+}
+
+func (d *CBORDecoder) readRecord() (Record, error) {
+	var r Record
+	major, n, stream, err := d.readHead()
+	if err != nil {
+		return r, err
+	}
+	if major != cborMap || stream || n != 2 {
+		return r, errCBORFormat
+	}
+	var gotAddr, gotData bool
+	for i := 0; i < 2; i++ {
+		key, err := d.readString(cborText)
+		if err != nil {
+			return r, err
+		}
+		switch string(key) {
+		case "address":
+			major, n, stream, err := d.readHead()
+			if err != nil {
+				return r, err
+			}
+			if major != cborUint || stream || n > 0xffffffff {
+				return r, fmt.Errorf("cbor: invalid record address")
+			}
+			r.Address = uint32(n)
+			gotAddr = true
+		case "data":
+			if r.Bytes, err = d.readString(cborBytes); err != nil {
+				return r, err
+			}
+			gotData = true
+		default:
+			return r, fmt.Errorf("cbor: unknown record key %q", key)
+		}
+	}
+	if !gotAddr || !gotData {
+		return r, errCBORFormat
+	}
+	return r, nil
+}
+
+// readString reads a definite-length byte or text string.
+func (d *CBORDecoder) readString(want byte) ([]byte, error) {
+	major, n, stream, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	if major != want || stream {
+		return nil, errCBORFormat
+	}
+	if n > 1<<32 {
+		return nil, fmt.Errorf("cbor: string too long")
+	}
+	// grow the buffer as data arrives, rather than trusting n
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readHead reads the initial byte and argument of a data item. For an
+// indefinite-length item, stream is true and n is zero.
+func (d *CBORDecoder) readHead() (major byte, n uint64, stream bool,
+	err error) {
+	ib, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, false, unexpectedEOF(err)
+	}
+	major, info := ib&0xe0, ib&0x1f
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info == cborStream:
+		return major, 0, true, nil
+	case info <= 27:
+		size = 1 << (info - 24)
+	default:
+		return 0, 0, false, errCBORFormat
+	}
+	var b [8]byte
+	if _, err := io.ReadFull(d.r, b[8-size:]); err != nil {
+		return 0, 0, false, unexpectedEOF(err)
+	}
+	return major, binary.BigEndian.Uint64(b[:]), false, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// MarshalCBOR returns the CBOR encoding of the segments and start
+// address of the Image.
+func (img *Image) MarshalCBOR() ([]byte, error) {
+	var buf bytes.Buffer
+	// the segments are a definite-length array, not a record stream
+	e := &CBOREncoder{w: bufio.NewWriter(&buf), started: true}
+	n := uint64(1)
+	if img.start != nil {
+		n++
+	}
+	e.writeHead(cborMap, n)
+	e.writeText("segments")
+	e.writeHead(cborArray, uint64(len(img.segs)))
+	for _, seg := range img.segs {
+		e.Encode(seg)
+	}
+	switch s := img.start; {
+	case s == nil:
+	case s.linear:
+		e.writeText("start")
+		e.writeHead(cborMap, 1)
+		e.writeText("eip")
+		e.writeHead(cborUint, uint64(s.eip))
+	default:
+		e.writeText("start")
+		e.writeHead(cborMap, 2)
+		e.writeText("cs")
+		e.writeHead(cborUint, uint64(s.cs))
+		e.writeText("ip")
+		e.writeHead(cborUint, uint64(s.ip))
+	}
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return buf.Bytes(), e.err
+}
+
+// UnmarshalCBOR replaces the contents of the Image with those encoded
+// in b, in the form returned by MarshalCBOR. The Image is unchanged if
+// there is an error.
+func (img *Image) UnmarshalCBOR(b []byte) error {
+	d := NewCBORDecoder(bytes.NewReader(b))
+	major, n, stream, err := d.readHead()
+	if err != nil {
+		return err
+	}
+	if major != cborMap || stream {
+		return errCBORImage
+	}
+	var loaded Image
+	for ; n > 0; n-- {
+		key, err := d.readString(cborText)
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "segments":
+			segs := &CBORDecoder{r: d.r}
+			for {
+				r, err := segs.Decode()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if uint64(r.Address)+uint64(len(r.Bytes)) > 1<<32 {
+					return fmt.Errorf("cbor: segment at %08X "+
+						"outside of address space", r.Address)
+				}
+				loaded.segs.add(r)
+			}
+		case "start":
+			if loaded.start, err = d.readStart(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("cbor: unknown Image key %q", key)
+		}
+	}
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return errCBORImage
+	}
+	*img = loaded
+	return nil
+}
+
+var errCBORImage = errors.New("cbor: not an Image")
+
+// readStart reads the start address of an Image.
+func (d *CBORDecoder) readStart() (*startAddr, error) {
+	major, n, stream, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborMap || stream || n < 1 || n > 2 {
+		return nil, errCBORImage
+	}
+	fields := make(map[string]uint64)
+	for ; n > 0; n-- {
+		key, err := d.readString(cborText)
+		if err != nil {
+			return nil, err
+		}
+		major, v, stream, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborUint || stream {
+			return nil, errCBORImage
+		}
+		fields[string(key)] = v
+	}
+	eip, hasEIP := fields["eip"]
+	cs, hasCS := fields["cs"]
+	ip, hasIP := fields["ip"]
+	switch {
+	case hasEIP && len(fields) == 1 && eip <= 0xffffffff:
+		return &startAddr{linear: true, eip: uint32(eip)}, nil
+	case hasCS && hasIP && len(fields) == 2 && cs <= 0xffff && ip <= 0xffff:
+		return &startAddr{cs: uint16(cs), ip: uint16(ip)}, nil
+	}
+	return nil, errors.New("cbor: invalid start address")
+}
@@ -0,0 +1,135 @@
+package ihex
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func TestCBOR(t *testing.T) {
+	recs := []Record{
+		{0x10, []byte("address gap")},
+		{0x12345678, []byte{}},
+		{0x100, bytes.Repeat([]byte{0xff}, 300)},
+	}
+	var buf bytes.Buffer
+	e := NewCBOREncoder(&buf)
+	for _, r := range recs {
+		if err := e.Encode(r); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	prefix := "9fa26761646472657373106464617461" + "4b" +
+		hex.EncodeToString([]byte("address gap"))
+	if !bytes.HasPrefix(buf.Bytes(), mustDecodeHex(prefix)) {
+		t.Errorf("incorrect encoding % x", buf.Bytes()[:32])
+	}
+
+	d := NewCBORDecoder(&buf)
+	var got []Record
+	for {
+		r, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		got = append(got, r)
+	}
+	checkRecords(t, got, recs)
+}
+
+func TestCBORDefinite(t *testing.T) {
+	// [{"data": h'01', "address": 2}]
+	input := mustDecodeHex("81a264646174614101676164647265737302")
+	d := NewCBORDecoder(bytes.NewReader(input))
+	r, err := d.Decode()
+	if err != nil || r.Address != 2 || !bytes.Equal(r.Bytes, []byte{1}) {
+		t.Error("incorrect record", r, err)
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Error("expected EOF, got", err)
+	}
+
+	bad := []string{"a0", "9fa1", "9fa2646461746101", "9f"}
+	for _, b := range bad {
+		d := NewCBORDecoder(bytes.NewReader(mustDecodeHex(b)))
+		if _, err := d.Decode(); err == nil || err == io.EOF {
+			t.Error("missed bad input", b, err)
+		}
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestImageCBOR(t *testing.T) {
+	img := &Image{}
+	img.WriteAt([]byte{1, 2}, 0x10)
+	img.WriteAt([]byte{3}, 0x80000000)
+	img.SetStartSegment(0x1234, 0x5678)
+	b, err := img.MarshalCBOR()
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	// {"segments": [{"address": 16, "data": h'0102'},
+	//   {"address": 2147483648, "data": h'03'}],
+	//  "start": {"cs": 4660, "ip": 22136}}
+	want := "a2" + "687365676d656e7473" + "82" +
+		"a2" + "6761646472657373" + "10" + "6464617461" + "420102" +
+		"a2" + "6761646472657373" + "1a80000000" + "6464617461" + "4103" +
+		"657374617274" + "a2" + "626373" + "191234" + "626970" + "195678"
+	if hex.EncodeToString(b) != want {
+		t.Errorf("expected\n%s\ngot\n%x", want, b)
+	}
+
+	var got Image
+	if err := got.UnmarshalCBOR(b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, got.Segments(), img.Segments())
+	if cs, ip, ok := got.CSIP(); !ok || cs != 0x1234 || ip != 0x5678 {
+		t.Errorf("incorrect start address %04X:%04X %v", cs, ip, ok)
+	}
+
+	img.SetStartLinear(0x100)
+	b, _ = img.MarshalCBOR()
+	if err := got.UnmarshalCBOR(b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if eip, ok := got.EIP(); !ok || eip != 0x100 {
+		t.Errorf("incorrect start address %08X %v", eip, ok)
+	}
+
+	bad := []string{
+		"a0ff",                         // trailing data
+		"a1687365676d656e7473",         // missing segments
+		"a1657374617274a1626373191234", // cs without ip
+		"a16462616e6b00",               // unknown key
+		"80",                           // not a map
+	}
+	for _, s := range bad {
+		if err := got.UnmarshalCBOR(mustDecodeHex(s)); err == nil {
+			t.Errorf("missed bad input %s", s)
+		}
+	}
+	if eip, ok := got.EIP(); !ok || eip != 0x100 || got.Size() != 3 {
+		t.Error("expected Image to be unchanged after errors")
+	}
+
+	var empty Image
+	b, _ = empty.MarshalCBOR()
+	if hex.EncodeToString(b) != "a1687365676d656e747380" {
+		t.Errorf("incorrect encoding of empty Image %x", b)
+	}
+}
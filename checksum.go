@@ -0,0 +1,176 @@
+package ihex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// A ChecksumAlgo describes how Image.Checksum computes a checksum.
+type ChecksumAlgo struct {
+	// Hash returns a hash.Hash whose sum is at most 8 bytes long.
+	Hash func() hash.Hash
+	// Fill is the value used for addresses that hold no data.
+	Fill byte
+}
+
+// SumBytes returns a ChecksumAlgo for the 32-bit sum of the bytes.
+func SumBytes(fill byte) ChecksumAlgo {
+	return ChecksumAlgo{func() hash.Hash { return new(byteSum) }, fill}
+}
+
+// CRC16CCITT returns a ChecksumAlgo for the CRC-16/CCITT-FALSE
+// checksum: polynomial 0x1021, initial value 0xFFFF, no reflection.
+func CRC16CCITT(fill byte) ChecksumAlgo {
+	return ChecksumAlgo{func() hash.Hash { return &crc16{0xffff} }, fill}
+}
+
+// CRC32 returns a ChecksumAlgo for the IEEE CRC-32 checksum, as used by
+// zlib and Ethernet.
+func CRC32(fill byte) ChecksumAlgo {
+	return ChecksumAlgo{func() hash.Hash { return crc32.NewIEEE() }, fill}
+}
+
+// Checksum returns the checksum of the data in the Image with addresses
+// in the range [start, end), computed by algo.
+func (img *Image) Checksum(start, end uint32, algo ChecksumAlgo) (uint64, error) {
+	if end < start {
+		return 0, errors.New("checksum range ends before it starts")
+	}
+	h := algo.Hash()
+	if h.Size() > 8 {
+		return 0, errors.New("checksum is longer than 8 bytes")
+	}
+	fill := bytes.Repeat([]byte{algo.Fill}, 4096)
+	writeFill := func(n uint64) {
+		writeRepeated(h, fill, n)
+	}
+	at := uint64(start)
+	for _, seg := range img.segs[img.segs.find(at):] {
+		lo := max(uint64(seg.Address), at)
+		if lo >= uint64(end) {
+			break
+		}
+		hi := min(uint64(seg.Address)+uint64(len(seg.Bytes)), uint64(end))
+		writeFill(lo - at)
+		h.Write(seg.Bytes[lo-uint64(seg.Address) : hi-uint64(seg.Address)])
+		at = hi
+	}
+	writeFill(uint64(end) - at)
+	var sum [8]byte
+	b := h.Sum(nil)
+	copy(sum[8-len(b):], b)
+	return binary.BigEndian.Uint64(sum[:]), nil
+}
+
+// writeRepeated writes n bytes to w, repeating the bytes of fill, which
+// must all be the same.
+func writeRepeated(w io.Writer, fill []byte, n uint64) {
+	for n > 0 {
+		m := min(n, uint64(len(fill)))
+		w.Write(fill[:m])
+		n -= m
+	}
+}
+
+// byteSum is a hash.Hash for the 32-bit sum of the bytes written.
+type byteSum uint32
+
+func (s *byteSum) Write(b []byte) (int, error) {
+	for _, c := range b {
+		*s += byteSum(c)
+	}
+	return len(b), nil
+}
+
+func (s *byteSum) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint32(b, uint32(*s))
+}
+
+func (s *byteSum) Reset()         { *s = 0 }
+func (s *byteSum) Size() int      { return 4 }
+func (s *byteSum) BlockSize() int { return 1 }
+
+// crc16 is a hash.Hash for the CRC-16/CCITT-FALSE checksum.
+type crc16 struct {
+	crc uint16
+}
+
+func (c *crc16) Write(b []byte) (int, error) {
+	for _, v := range b {
+		c.crc ^= uint16(v) << 8
+		for range 8 {
+			if c.crc&0x8000 != 0 {
+				c.crc = c.crc<<1 ^ 0x1021
+			} else {
+				c.crc <<= 1
+			}
+		}
+	}
+	return len(b), nil
+}
+
+func (c *crc16) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint16(b, c.crc)
+}
+
+func (c *crc16) Reset()         { c.crc = 0xffff }
+func (c *crc16) Size() int      { return 2 }
+func (c *crc16) BlockSize() int { return 1 }
+
+// A BlockHash is the digest of a block of an Image.
+type BlockHash struct {
+	Address uint32
+	Sum     []byte
+}
+
+// BlockHashes divides the address space into blocks of blockSize bytes,
+// each starting at a multiple of blockSize, and returns the digest
+// computed by a hash.Hash from newHash of each block that holds data,
+// in address order. Addresses in a block that hold no data are hashed
+// as 0xFF, the value of erased flash memory.
+func (img *Image) BlockHashes(blockSize uint32, newHash func() hash.Hash) ([]BlockHash, error) {
+	if blockSize == 0 {
+		return nil, errors.New("block size must be greater than zero")
+	}
+	size := uint64(blockSize)
+	fill := bytes.Repeat([]byte{0xff}, int(min(size, 4096)))
+	var (
+		hashes []BlockHash
+		h      hash.Hash
+		start  uint64 // of the block being hashed
+		at     uint64 // the next address in it to hash
+	)
+	finish := func() {
+		writeRepeated(h, fill, min(start+size, 1<<32)-at)
+		hashes = append(hashes, BlockHash{uint32(start), h.Sum(nil)})
+		h = nil
+	}
+	for _, seg := range img.segs {
+		addr := uint64(seg.Address)
+		b := seg.Bytes
+		for len(b) > 0 {
+			if h != nil && addr >= start+size {
+				finish()
+			}
+			if h == nil {
+				h = newHash()
+				start = addr - addr%size
+				at = start
+			}
+			writeRepeated(h, fill, addr-at)
+			n := min(uint64(len(b)), start+size-addr)
+			h.Write(b[:n])
+			addr += n
+			at = addr
+			b = b[n:]
+		}
+	}
+	if h != nil {
+		finish()
+	}
+	return hashes, nil
+}
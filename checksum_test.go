@@ -0,0 +1,68 @@
+package ihex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	img := &Image{}
+	img.WriteAt([]byte("123456789"), 0x100)
+	img.WriteAt([]byte{1}, 0x10)
+	tests := []struct {
+		start, end uint32
+		algo       ChecksumAlgo
+		want       uint64
+	}{
+		{0x100, 0x109, SumBytes(0), 0x1dd},
+		{0x100, 0x109, CRC16CCITT(0), 0x29b1},
+		{0x100, 0x109, CRC32(0), 0xcbf43926},
+		{0x0e, 0x12, SumBytes(0xff), 0x2fe},
+		{0x20, 0x20, SumBytes(0xff), 0},
+	}
+	for _, tt := range tests {
+		got, err := img.Checksum(tt.start, tt.end, tt.algo)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if got != tt.want {
+			t.Errorf("%X-%X: expected %X, got %X", tt.start, tt.end, tt.want, got)
+		}
+	}
+	if _, err := img.Checksum(2, 1, CRC32(0)); err == nil {
+		t.Error("expected error for reversed range")
+	}
+}
+
+func TestBlockHashes(t *testing.T) {
+	var img Image
+	img.WriteAt([]byte{1, 2, 3, 4}, 0x100)
+	img.WriteAt([]byte{5, 6, 7, 8}, 0x10e)
+	hashes, err := img.BlockHashes(8, sha256.New)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	blocks := []struct {
+		addr uint32
+		data string
+	}{
+		{0x100, "\x01\x02\x03\x04\xff\xff\xff\xff"},
+		{0x108, "\xff\xff\xff\xff\xff\xff\x05\x06"},
+		{0x110, "\x07\x08\xff\xff\xff\xff\xff\xff"},
+	}
+	if len(hashes) != len(blocks) {
+		t.Fatalf("expected %d hashes, got %d", len(blocks), len(hashes))
+	}
+	for i, b := range blocks {
+		sum := sha256.Sum256([]byte(b.data))
+		if hashes[i].Address != b.addr || !bytes.Equal(hashes[i].Sum, sum[:]) {
+			t.Errorf("%d: expected %X %X, got %X %X",
+				i, b.addr, sum, hashes[i].Address, hashes[i].Sum)
+		}
+	}
+
+	if _, err := img.BlockHashes(0, sha256.New); err == nil {
+		t.Error("missed zero block size")
+	}
+}
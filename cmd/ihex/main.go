@@ -0,0 +1,413 @@
+// Command ihex inspects, converts, checks, compares and merges Intel
+// HEX files.
+//
+// Usage:
+//
+//	ihex info [-o OUT] [-report markdown|html] FILE
+//	ihex list [-map MAPFILE] FILE
+//	ihex convert [-o OUT] [-fill BYTE] [-base ADDR] [-transform EXPR] FILE
+//	ihex verify FILE...
+//	ihex diff [-mask MASK] FILE1 FILE2
+//	ihex merge [-o OUT] [-policy error|first|last|overlap] FILE...
+//	ihex run PIPELINE
+//
+// The info command describes the data and records in a file, or with
+// -report, writes a table of its layout in Markdown or HTML.
+//
+// The list command prints each record of a file with its decoded fields
+// and whether its checksum is correct, and with -map, the symbol and
+// section from a GNU ld or LLD map file that hold each data record.
+//
+// The convert command converts a HEX file to a flat binary image, or a
+// file whose name ends in ".bin" to a HEX file with its data starting
+// at the base address. Either way, the data is passed through the
+// transforms given by -transform, in the syntax of ihex.ParseTransform,
+// such as "crop(0x8000, 0x20000) | offset(-0x8000)".
+//
+// The verify command checks files for errors, reporting all of them.
+//
+// The diff command lists the ranges of addresses at which the data in
+// two files differs, ignoring those at which the file given by -mask
+// holds a nonzero byte.
+//
+// The merge command combines files into one, with conflicting data
+// resolved by the policy.
+//
+// The run command runs the pipeline described by a JSON file, as
+// documented for ihex.Pipeline, with file names relative to that of the
+// pipeline. Its inputs and outputs can also be in the "srec" format of
+// package srec.
+//
+// Output goes to standard output unless -o is given, and flags may
+// follow the file names.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edmccard/ihex"
+	_ "github.com/edmccard/ihex/srec" // registers the "srec" format
+)
+
+const usage = `usage:
+	ihex info [-o OUT] [-report markdown|html] FILE
+	ihex list [-map MAPFILE] FILE
+	ihex convert [-o OUT] [-fill BYTE] [-base ADDR] [-transform EXPR] FILE
+	ihex verify FILE...
+	ihex diff [-mask MASK] FILE1 FILE2
+	ihex merge [-o OUT] [-policy error|first|last|overlap] FILE...
+	ihex run PIPELINE
+`
+
+// errUsage is returned for a command line that cannot be run.
+var errUsage = errors.New("invalid usage")
+
+// errFailed is returned when verify finds errors, or diff finds
+// differences, which it has already reported.
+var errFailed = errors.New("verification failed")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage):
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	case errors.Is(err, errFailed):
+		os.Exit(1)
+	default:
+		fmt.Fprintln(os.Stderr, "ihex:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command given by args, writing its output to stdout and
+// any diagnostics to stderr.
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("o", "", "write output to `file`")
+	switch cmd {
+	case "info":
+		report := fs.String("report", "", "write a `format` report: markdown or html")
+		files, err := parseArgs(fs, args, 1, 1)
+		if err != nil {
+			return err
+		}
+		tmpl, ok := reports[*report]
+		if !ok {
+			return fmt.Errorf("invalid report format %q", *report)
+		}
+		return withOutput(*out, stdout, func(w io.Writer) error {
+			return info(files[0], w, tmpl)
+		})
+	case "list":
+		mapFile := fs.String("map", "", "annotate data with symbols from linker map `file`")
+		files, err := parseArgs(fs, args, 1, 1)
+		if err != nil {
+			return err
+		}
+		return list(files[0], *mapFile, stdout)
+	case "convert":
+		fill := fs.String("fill", "FF", "hex `byte` to fill gaps with")
+		base := fs.String("base", "0", "hex `address` of binary input")
+		transform := fs.String("transform", "", "transform data with `expr`")
+		files, err := parseArgs(fs, args, 1, 1)
+		if err != nil {
+			return err
+		}
+		pad, err := strconv.ParseUint(*fill, 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid fill byte %q", *fill)
+		}
+		addr, err := strconv.ParseUint(*base, 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid base address %q", *base)
+		}
+		opts := []ihex.BinaryOption{ihex.PadByte(byte(pad))}
+		if *transform != "" {
+			t, err := ihex.ParseTransform(*transform)
+			if err != nil {
+				return fmt.Errorf("invalid transform: %v", err)
+			}
+			opts = append(opts, ihex.TransformData(t))
+		}
+		return withOutput(*out, stdout, func(w io.Writer) error {
+			return convert(files[0], w, uint32(addr), opts)
+		})
+	case "verify":
+		files, err := parseArgs(fs, args, 1, -1)
+		if err != nil {
+			return err
+		}
+		return verify(files, stdout)
+	case "diff":
+		mask := fs.String("mask", "", "ignore addresses with nonzero bytes in `file`")
+		files, err := parseArgs(fs, args, 2, 2)
+		if err != nil {
+			return err
+		}
+		return diff(files, *mask, stdout)
+	case "merge":
+		policyName := fs.String("policy", "error",
+			"how to resolve conflicts: error, first, last or overlap")
+		files, err := parseArgs(fs, args, 1, -1)
+		if err != nil {
+			return err
+		}
+		policy, ok := policies[*policyName]
+		if !ok {
+			return fmt.Errorf("invalid policy %q", *policyName)
+		}
+		return withOutput(*out, stdout, func(w io.Writer) error {
+			return merge(files, w, policy)
+		})
+	case "run":
+		files, err := parseArgs(fs, args, 1, 1)
+		if err != nil {
+			return err
+		}
+		return ihex.RunPipelineFile(files[0])
+	}
+	return errUsage
+}
+
+var policies = map[string]ihex.ConflictPolicy{
+	"error":   ihex.ConflictError,
+	"first":   ihex.FirstWins,
+	"last":    ihex.LastWins,
+	"overlap": ihex.OverlapError,
+}
+
+// parseArgs parses the flags in args, which may come before or after
+// the other arguments, and returns the other arguments, of which there
+// must be at least lo, and at most hi unless hi is negative.
+func parseArgs(fs *flag.FlagSet, args []string, lo, hi int) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+	if len(rest) < lo || (hi >= 0 && len(rest) > hi) {
+		return nil, errUsage
+	}
+	return rest, nil
+}
+
+// withOutput calls write with the file named name, or with stdout if
+// name is empty. The file is removed if write fails.
+func withOutput(name string, stdout io.Writer, write func(io.Writer) error) error {
+	if name == "" {
+		return write(stdout)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+	}
+	return err
+}
+
+// reports holds the templates for the -report flag of info, with nil
+// for the default plain text.
+var reports = map[string]ihex.ReportTemplate{
+	"":         nil,
+	"markdown": ihex.MarkdownReport,
+	"html":     ihex.HTMLReport,
+}
+
+func info(name string, w io.Writer, tmpl ihex.ReportTemplate) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s, err := ihex.Summarize(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if tmpl != nil {
+		return ihex.RenderReport(w, tmpl, s)
+	}
+	fmt.Fprintf(w, "data bytes: %d\n", s.Bytes)
+	if s.Bytes > 0 {
+		fmt.Fprintf(w, "address range: %08X-%08X\n", s.MinAddress, s.MaxAddress)
+	}
+	fmt.Fprintf(w, "regions: %d\n", len(s.Regions))
+	for _, r := range s.Regions {
+		fmt.Fprintf(w, "\t%08X-%08X (%d bytes)\n",
+			r.Address, uint64(r.Address)+uint64(r.Len)-1, r.Len)
+	}
+	types := make([]int, 0, len(s.Types))
+	for t := range s.Types {
+		types = append(types, int(t))
+	}
+	sort.Ints(types)
+	fmt.Fprintln(w, "records:")
+	for _, t := range types {
+		fmt.Fprintf(w, "\ttype %d: %d\n", t, s.Types[byte(t)])
+	}
+	if s.HasEIP {
+		fmt.Fprintf(w, "start address: %08X\n", s.EIP)
+	}
+	if s.HasCSIP {
+		fmt.Fprintf(w, "start segment address: %04X:%04X\n", s.CS, s.IP)
+	}
+	return nil
+}
+
+func list(name, mapName string, w io.Writer) error {
+	var opts []ihex.ListingOption
+	if mapName != "" {
+		f, err := os.Open(mapName)
+		if err != nil {
+			return err
+		}
+		m, err := ihex.ReadLinkerMap(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", mapName, err)
+		}
+		opts = append(opts, ihex.Annotate(m))
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := ihex.Listing(w, f, opts...); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func convert(name string, w io.Writer, base uint32, opts []ihex.BinaryOption) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(name), ".bin") {
+		err = ihex.FromBinary(f, w, base, opts...)
+	} else {
+		err = ihex.ToBinary(f, w, opts...)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func verify(names []string, w io.Writer) error {
+	failed := false
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			failed = true
+			continue
+		}
+		p := ihex.NewParser(f, ihex.CollectErrors())
+		for p.Parse() {
+		}
+		f.Close()
+		errs := p.Errors()
+		for _, e := range errs {
+			fmt.Fprintf(w, "%s:%d: %s\n", name, e.Line, e.Msg)
+		}
+		// an error that stopped the Parser is not among those collected
+		err = p.Err()
+		if err != nil && (len(errs) == 0 || err != error(errs[0])) {
+			fmt.Fprintf(w, "%s: %v\n", name, err)
+		}
+		if err != nil {
+			failed = true
+			continue
+		}
+		fmt.Fprintf(w, "%s: OK\n", name)
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func diff(names []string, maskName string, w io.Writer) error {
+	var imgs []*ihex.Image
+	for _, name := range append(names, maskName) {
+		if name == "" {
+			imgs = append(imgs, &ihex.Image{})
+			continue
+		}
+		img, err := readImage(name)
+		if err != nil {
+			return err
+		}
+		imgs = append(imgs, img)
+	}
+	diffs := ihex.DiffMasked(imgs[0], imgs[1], imgs[2])
+	for _, d := range diffs {
+		fmt.Fprintf(w, "%08X-%08X %s (%d bytes)\n",
+			d.Address, uint64(d.Address)+uint64(d.Len)-1, d.Kind, d.Len)
+	}
+	if len(diffs) > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+// readImage reads the named HEX file into an Image.
+func readImage(name string) (*ihex.Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := ihex.ReadImage(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return img, nil
+}
+
+func merge(names []string, w io.Writer, policy ihex.ConflictPolicy) error {
+	var srcs []io.Reader
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		srcs = append(srcs, f)
+	}
+	err := ihex.Merge(w, srcs, policy)
+	if serr, ok := err.(ihex.SourceError); ok {
+		return fmt.Errorf("%s: %v", names[serr.Source], serr.Err)
+	}
+	return err
+}
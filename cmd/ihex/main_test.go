@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const boot = `:0400000001020304F2
+:00000001FF
+`
+
+const app = `:0400080005060708DA
+:0400000500000100F6
+:00000001FF
+`
+
+// writeFiles writes each of the named files to a temporary directory,
+// and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runArgs(t *testing.T, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := run(args, &out, io.Discard)
+	return out.String(), err
+}
+
+func TestInfo(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"app.hex": app})
+	out, err := runArgs(t, "info", filepath.Join(dir, "app.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"data bytes: 4\n",
+		"address range: 00000008-0000000B\n",
+		"\ttype 5: 1\n",
+		"start address: 00000100\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	out, err = runArgs(t, "info", "-report", "markdown", filepath.Join(dir, "app.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "| 0x00000008 | 0x0000000B | 4 |\n") {
+		t.Errorf("expected a Markdown table, got\n%s", out)
+	}
+	if _, err := runArgs(t, "info", "-report", "pdf", filepath.Join(dir, "app.hex")); err == nil {
+		t.Error("expected error for an unknown report format")
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.hex": ":0400080005060708DB\n" + app[len(":0400080005060708DA\n"):],
+	})
+	out, err := runArgs(t, "list", filepath.Join(dir, "app.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 ||
+		!strings.Contains(lines[0], "00000008-0000000B") ||
+		!strings.HasSuffix(lines[0], "DB FAIL (computed DA)") ||
+		!strings.Contains(lines[1], "EIP 00000100") ||
+		!strings.HasSuffix(lines[2], "FF ok") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	dir = writeFiles(t, map[string]string{
+		"app.hex": app,
+		"app.map": "             VMA              LMA     Size Align Out     In      Symbol\n" +
+			"               8                8        4     4 .data\n" +
+			"               8                8        0     1                 table\n",
+	})
+	out, err = runArgs(t, "list", "-map", filepath.Join(dir, "app.map"),
+		filepath.Join(dir, "app.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "DA ok  ; table (.data)\n") {
+		t.Errorf("expected a symbol annotation, got\n%s", out)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"app.hex": app})
+	bin := filepath.Join(dir, "app.bin")
+	_, err := runArgs(t, "convert", filepath.Join(dir, "app.hex"), "-o", bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, []byte{5, 6, 7, 8}) {
+		t.Errorf("expected 05060708, got %X", b)
+	}
+	out, err := runArgs(t, "convert", "-base", "8", bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ":0400080005060708DA\n:00000001FF\n"; out != want {
+		t.Errorf("expected\n%s, got\n%s", want, out)
+	}
+
+	out, err = runArgs(t, "convert", "-transform", "crop(9, 11) | offset(-9)",
+		filepath.Join(dir, "app.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "\x06\x07" {
+		t.Errorf("expected 0607, got %X", out)
+	}
+	out, err = runArgs(t, "convert", "-base", "8", "-transform", "split(2)", bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ":020008000506EB\n:02000A000708E5\n:00000001FF\n"; out != want {
+		t.Errorf("expected\n%s, got\n%s", want, out)
+	}
+	_, err = runArgs(t, "convert", "-transform", "bogus()", bin)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid transform: ") {
+		t.Errorf("expected an invalid transform error, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"boot.hex": boot,
+		"bad.hex":  ":0400000001020304F3\n:0100000041\n:00000001FF\n",
+	})
+	good, bad := filepath.Join(dir, "boot.hex"), filepath.Join(dir, "bad.hex")
+	out, err := runArgs(t, "verify", good, bad)
+	if !errors.Is(err, errFailed) {
+		t.Errorf("expected verification to fail, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 || lines[0] != good+": OK" ||
+		!strings.HasPrefix(lines[1], bad+":1: ") ||
+		!strings.HasPrefix(lines[2], bad+":2: ") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if _, err := runArgs(t, "verify", good); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"boot.hex": boot,
+		"unit.hex": ":040000000142034472\n:00000001FF\n",
+		"mask.hex": ":0100010001FD\n:00000001FF\n",
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+	out, err := runArgs(t, "diff", path("boot.hex"), path("unit.hex"))
+	if !errors.Is(err, errFailed) {
+		t.Errorf("expected differences, got %v", err)
+	}
+	want := "00000001-00000001 changed (1 bytes)\n" +
+		"00000003-00000003 changed (1 bytes)\n"
+	if out != want {
+		t.Errorf("expected\n%s, got\n%s", want, out)
+	}
+	out, err = runArgs(t, "diff", "-mask", path("mask.hex"),
+		path("boot.hex"), path("unit.hex"))
+	if !errors.Is(err, errFailed) || out != want[len(want)/2:] {
+		t.Errorf("expected the masked byte to be ignored, got %v\n%s", err, out)
+	}
+	if _, err := runArgs(t, "diff", path("boot.hex"), path("boot.hex")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"boot.hex":  boot,
+		"app.hex":   app,
+		"clash.hex": ":0100000041BE\n:00000001FF\n",
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+	outPath := path("out.hex")
+	_, err := runArgs(t, "merge", path("boot.hex"), path("app.hex"), "-o", outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ":0400000001020304F2\n:0400080005060708DA\n" +
+		":0400000500000100F6\n:00000001FF\n"
+	if string(b) != want {
+		t.Errorf("expected\n%s, got\n%s", want, b)
+	}
+
+	_, err = runArgs(t, "merge", "-o", outPath, path("boot.hex"), path("clash.hex"))
+	if err == nil || !strings.HasPrefix(err.Error(), path("clash.hex")+": ") {
+		t.Errorf("expected a conflict in clash.hex, got %v", err)
+	}
+	if _, err := os.Stat(outPath); !os.IsNotExist(err) {
+		t.Errorf("expected output to be removed after an error")
+	}
+	out, err := runArgs(t, "merge", "-policy", "last", path("boot.hex"), path("clash.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, ":0400000041020304B2\n") {
+		t.Errorf("expected the last data to win, got\n%s", out)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"boot.hex": boot,
+		"app.hex":  app,
+		"pipeline.json": `{
+			"inputs": [{"file": "boot.hex"}, {"file": "app.hex"}],
+			"outputs": [{"file": "out.bin", "format": "bin", "fill": 0},
+				{"file": "out.s37", "format": "srec"}]
+		}`,
+		"bad.json": `{"inputs": [{"file": "missing.hex"}]}`,
+	})
+	if _, err := runArgs(t, "run", filepath.Join(dir, "pipeline.json")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "out.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{1, 2, 3, 4, 0, 0, 0, 0, 5, 6, 7, 8}; !bytes.Equal(b, want) {
+		t.Errorf("expected % X, got % X", want, b)
+	}
+	b, err = os.ReadFile(filepath.Join(dir, "out.s37"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "S3") {
+		t.Errorf("expected S-records, got\n%s", b)
+	}
+	if _, err := runArgs(t, "run", filepath.Join(dir, "bad.json")); err == nil {
+		t.Error("expected error for a missing input")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"frobnicate"},
+		{"info"},
+		{"info", "a.hex", "b.hex"},
+		{"list"},
+		{"run", "a.json", "b.json"},
+		{"merge", "-bogus", "a.hex"},
+	} {
+		if _, err := runArgs(t, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%q: expected a usage error, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ihex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// A Codec reads and writes Images in a file format, so that formats
+// implemented outside this package, such as Motorola S-records by
+// package srec, can be chosen by name or detected from their contents
+// by Convert and by a Pipeline, as the built-in "hex" and "bin" formats
+// are.
+type Codec struct {
+	Name string
+	// Sniff returns the priority with which the codec claims a file
+	// that starts with head, or 0 if it does not; the codec that returns
+	// the highest priority is chosen. A nil Sniff never claims a file.
+	Sniff func(head []byte) int
+	Caps  CodecCaps
+	Read  func(r io.Reader) (*Image, error)
+	Write func(w io.Writer, img *Image) error
+}
+
+// CodecCaps are flags describing what a Codec can do.
+type CodecCaps uint
+
+const (
+	// CodecStreaming marks a format that can be read record by record,
+	// without holding the whole file; one without it is image-only.
+	CodecStreaming CodecCaps = 1 << iota
+	// CodecStart marks a format that can hold a start address.
+	CodecStart
+)
+
+// sniffLen is the length of the start of a file passed to Sniff.
+const sniffLen = 512
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = func() map[string]Codec {
+		m := make(map[string]Codec)
+		for _, c := range []Codec{hexCodec, binCodec} {
+			m[c.Name] = c
+		}
+		return m
+	}()
+)
+
+var hexCodec = Codec{
+	Name: "hex",
+	Sniff: func(head []byte) int {
+		head = bytes.TrimLeft(head, " \t\r\n\ufeff")
+		if len(head) > 0 && head[0] == ':' {
+			return 10
+		}
+		return 0
+	},
+	Caps: CodecStreaming | CodecStart,
+	Read: func(r io.Reader) (*Image, error) {
+		return ReadImage(r)
+	},
+	Write: func(w io.Writer, img *Image) error {
+		hw := NewWriter(w)
+		if err := hw.WriteImage(img); err != nil {
+			return err
+		}
+		return hw.Close()
+	},
+}
+
+var binCodec = Codec{
+	Name: "bin",
+	Read: func(r io.Reader) (*Image, error) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		img := &Image{}
+		img.WriteAt(b, 0)
+		return img, nil
+	},
+	Write: func(w io.Writer, img *Image) error {
+		_, err := w.Write(img.Bytes(0xff))
+		return err
+	},
+}
+
+// RegisterCodec makes a Codec available by its name. It panics if the
+// name is empty, if a Codec of that name is already registered, or if
+// Read or Write is nil. It is usually called from the init function of
+// the package that implements the format.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if c.Name == "" || c.Read == nil || c.Write == nil {
+		panic("ihex: RegisterCodec with incomplete codec")
+	}
+	if _, dup := codecs[c.Name]; dup {
+		panic("ihex: RegisterCodec called twice for codec " + c.Name)
+	}
+	codecs[c.Name] = c
+}
+
+// LookupCodec returns the Codec registered with name.
+func LookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// Codecs returns the registered Codecs, sorted by name.
+func Codecs() []Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	list := make([]Codec, 0, len(codecs))
+	for _, c := range codecs {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// DetectCodec returns the Codec that claims a file starting with head
+// with the highest priority, preferring the first by name if more than
+// one does. It returns ok false if none claims it.
+func DetectCodec(head []byte) (c Codec, ok bool) {
+	best := 0
+	for _, codec := range Codecs() {
+		if codec.Sniff == nil {
+			continue
+		}
+		if pri := codec.Sniff(head); pri > best {
+			c, ok, best = codec, true, pri
+		}
+	}
+	return c, ok
+}
+
+// Convert reads a file in the format named from from r and writes it
+// to w in the format named to. If from is empty, the format is detected
+// by DetectCodec.
+func Convert(w io.Writer, to string, r io.Reader, from string) error {
+	dst, ok := LookupCodec(to)
+	if !ok {
+		return fmt.Errorf("unknown format %q", to)
+	}
+	br := bufio.NewReaderSize(r, sniffLen)
+	src, err := findCodec(br, from)
+	if err != nil {
+		return err
+	}
+	img, err := src.Read(br)
+	if err != nil {
+		return err
+	}
+	return dst.Write(w, img)
+}
+
+// findCodec returns the Codec named name, or the one detected from the
+// start of r if name is empty.
+func findCodec(r *bufio.Reader, name string) (Codec, error) {
+	if name != "" {
+		c, ok := LookupCodec(name)
+		if !ok {
+			return Codec{}, fmt.Errorf("unknown format %q", name)
+		}
+		return c, nil
+	}
+	head, _ := r.Peek(sniffLen)
+	c, ok := DetectCodec(head)
+	if !ok {
+		return Codec{}, errors.New("unrecognized format")
+	}
+	return c, nil
+}
@@ -0,0 +1,74 @@
+package ihex
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	const input = "\n:0400000001020304F2\n:0400000500000100F6\n:00000001FF\n"
+	var bin bytes.Buffer
+	if err := Convert(&bin, "bin", strings.NewReader(input), ""); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := []byte{1, 2, 3, 4}; !bytes.Equal(bin.Bytes(), want) {
+		t.Errorf("expected % X, got % X", want, bin.Bytes())
+	}
+	var text strings.Builder
+	if err := Convert(&text, "hex", &bin, "bin"); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := ":0400000001020304F2\n:00000001FF\n"; text.String() != want {
+		t.Errorf("expected\n%s, got\n%s", want, text.String())
+	}
+
+	for _, tt := range []struct{ to, from, input, err string }{
+		{"elf", "", input, `unknown format "elf"`},
+		{"bin", "elf", input, `unknown format "elf"`},
+		{"hex", "", "\x7fELF", "unrecognized format"},
+	} {
+		err := Convert(io.Discard, tt.to, strings.NewReader(tt.input), tt.from)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("expected %q, got %v", tt.err, err)
+		}
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	c := Codec{
+		Name: "codec-test",
+		Sniff: func(head []byte) int {
+			if bytes.HasPrefix(head, []byte(":test")) {
+				return 20
+			}
+			return 0
+		},
+		Read:  binCodec.Read,
+		Write: binCodec.Write,
+	}
+	RegisterCodec(c)
+	if got, ok := LookupCodec("codec-test"); !ok || got.Name != c.Name {
+		t.Error("registered codec not found")
+	}
+	if got, ok := DetectCodec([]byte(":test")); !ok || got.Name != c.Name {
+		t.Errorf("expected codec-test to win, got %q", got.Name)
+	}
+	if got, ok := DetectCodec([]byte(":00000001FF")); !ok || got.Name != "hex" {
+		t.Errorf("expected hex, got %q", got.Name)
+	}
+	if hex, _ := LookupCodec("hex"); hex.Caps&CodecStreaming == 0 {
+		t.Error("expected hex to be a streaming format")
+	}
+	for _, bad := range []Codec{c, {Name: "codec-test-2"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %q to panic", bad.Name)
+				}
+			}()
+			RegisterCodec(bad)
+		}()
+	}
+}
@@ -0,0 +1,129 @@
+package ihex
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A DiffKind describes how a DiffRegion differs between two files.
+type DiffKind int
+
+const (
+	OnlyInA DiffKind = iota // data present only in the first file
+	OnlyInB                 // data present only in the second file
+	Changed                 // data present in both, with different values
+)
+
+var diffKindNames = [...]string{"only in A", "only in B", "changed"}
+
+func (k DiffKind) String() string {
+	if int(k) < len(diffKindNames) {
+		return diffKindNames[k]
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// A DiffRegion is a range of addresses at which two files differ.
+type DiffRegion struct {
+	Kind    DiffKind
+	Address uint32
+	Len     int
+}
+
+// Diff compares the data in the Intel HEX files read from a and b,
+// returning the regions in which they differ, in address order. Only
+// the data is compared, so files that are laid out differently but hold
+// the same data have no differences.
+func Diff(a, b io.Reader) ([]DiffRegion, error) {
+	imgA, err := ReadImage(a)
+	if err != nil {
+		return nil, SourceError{Source: 0, Err: err}
+	}
+	imgB, err := ReadImage(b)
+	if err != nil {
+		return nil, SourceError{Source: 1, Err: err}
+	}
+	return imgA.Diff(imgB), nil
+}
+
+// Diff returns the regions in which the data in img and other differ,
+// with img as the first file, in address order.
+func (img *Image) Diff(other *Image) []DiffRegion {
+	// between consecutive cuts, each image either has data for every
+	// address or for none
+	var cuts []uint64
+	for _, segs := range []spans{img.segs, other.segs} {
+		for _, seg := range segs {
+			start := uint64(seg.Address)
+			cuts = append(cuts, start, start+uint64(len(seg.Bytes)))
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+	var diffs []DiffRegion
+	add := func(kind DiffKind, addr uint64, n int) {
+		if last := len(diffs) - 1; last >= 0 && diffs[last].Kind == kind &&
+			uint64(diffs[last].Address)+uint64(diffs[last].Len) == addr {
+			diffs[last].Len += n
+			return
+		}
+		diffs = append(diffs, DiffRegion{kind, uint32(addr), n})
+	}
+	for i := 1; i < len(cuts); i++ {
+		lo, hi := cuts[i-1], cuts[i]
+		if lo == hi {
+			continue
+		}
+		a, b := img.segs.slice(lo, hi), other.segs.slice(lo, hi)
+		switch {
+		case a == nil && b == nil:
+		case b == nil:
+			add(OnlyInA, lo, int(hi-lo))
+		case a == nil:
+			add(OnlyInB, lo, int(hi-lo))
+		default:
+			for j := range a {
+				if a[j] != b[j] {
+					add(Changed, lo+uint64(j), 1)
+				}
+			}
+		}
+	}
+	return diffs
+}
+
+// DiffMasked is like a.Diff(b), but ignores the addresses at which mask
+// holds a nonzero byte, such as those of calibration data or serial
+// numbers that are expected to differ from one unit to the next.
+func DiffMasked(a, b, mask *Image) []DiffRegion {
+	var diffs []DiffRegion
+	for _, d := range a.Diff(b) {
+		start := uint64(d.Address)
+		end := start + uint64(d.Len)
+		for addr := start; addr < end; addr++ {
+			if m := mask.segs.slice(addr, addr+1); m != nil && m[0] != 0 {
+				if addr > start {
+					diffs = append(diffs, DiffRegion{d.Kind, uint32(start),
+						int(addr - start)})
+				}
+				start = addr + 1
+			}
+		}
+		if end > start {
+			diffs = append(diffs, DiffRegion{d.Kind, uint32(start),
+				int(end - start)})
+		}
+	}
+	return diffs
+}
+
+// slice returns the data for the addresses [lo, hi), which must either
+// all be held in s or all be missing, in which case it returns nil.
+func (s spans) slice(lo, hi uint64) []byte {
+	i := s.find(lo)
+	if i == len(s) || uint64(s[i].Address) > lo {
+		return nil
+	}
+	start := uint64(s[i].Address)
+	return s[i].Bytes[lo-start : hi-start]
+}
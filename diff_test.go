@@ -0,0 +1,63 @@
+package ihex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := ":0400000001020304F2\n:00000001FF\n"
+	b := `:0100000001FE
+:020002000903F0
+:020004000506EF
+:00000001FF
+`
+	diffs, err := Diff(strings.NewReader(a), strings.NewReader(b))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := []DiffRegion{
+		{OnlyInA, 1, 1},
+		{Changed, 2, 2},
+		{OnlyInB, 4, 2},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, diffs)
+	}
+	for i := range diffs {
+		if diffs[i] != want[i] {
+			t.Errorf("region %d: expected %v, got %v", i, want[i], diffs[i])
+		}
+	}
+
+	diffs, err = Diff(strings.NewReader(a), strings.NewReader(a))
+	if err != nil || len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v, %v", diffs, err)
+	}
+	_, err = Diff(strings.NewReader(a), strings.NewReader(":00000001FE\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "input 1: ") {
+		t.Errorf("expected error from input 1, got %v", err)
+	}
+}
+
+func TestDiffMasked(t *testing.T) {
+	var a, b, mask Image
+	a.WriteAt([]byte{1, 2, 3, 4, 5, 6, 7, 8}, 0x10)
+	b.WriteAt([]byte{1, 9, 9, 9, 5, 6, 9, 8, 9}, 0x10)
+	// ignore the serial number at 0x12 and the byte only in b
+	mask.WriteAt([]byte{1, 0, 0, 0, 0, 0, 1}, 0x12)
+	got := DiffMasked(&a, &b, &mask)
+	want := []DiffRegion{
+		{Changed, 0x11, 1},
+		{Changed, 0x13, 1},
+		{Changed, 0x16, 1},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := DiffMasked(&a, &b, &Image{}); len(got) != 3 ||
+		got[2] != (DiffRegion{OnlyInB, 0x18, 1}) {
+		t.Errorf("expected the unmasked diff, got %v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package ihex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Digests makes the Writer copy the text it writes to text, and the data
+// it is given to data, in the form of a flat binary image like that
+// returned by Image.Bytes, with any gaps between the data filled with
+// fill. Either may be nil. Passing hashes, or io.MultiWriters of several
+// hashes, computes digests of both forms while the file is written. For
+// the binary form, data must be written in order of address, as it is
+// by WriteImage; the Writer fails if it is not.
+func Digests(text, data io.Writer, fill byte) WriterOption {
+	return func(w *Writer) {
+		w.text = text
+		w.data = nil
+		if data != nil {
+			w.data = &dataDigest{w: data, fill: fill}
+		}
+	}
+}
+
+// A dataDigest writes the data given to a Writer as a flat binary image.
+type dataDigest struct {
+	w       io.Writer
+	fill    byte
+	fillBuf []byte
+	next    uint64 // the address after the last byte written
+	started bool
+}
+
+func (d *dataDigest) write(addr uint32, b []byte) error {
+	if d.started && uint64(addr) < d.next {
+		return fmt.Errorf("data at %08X out of order for digest", addr)
+	}
+	if d.started && uint64(addr) > d.next {
+		if d.fillBuf == nil {
+			d.fillBuf = bytes.Repeat([]byte{d.fill}, 256)
+		}
+		writeRepeated(d.w, d.fillBuf, uint64(addr)-d.next)
+	}
+	d.w.Write(b)
+	d.next = uint64(addr) + uint64(len(b))
+	d.started = true
+	return nil
+}
+
+// WriteFileWithDigests writes the Image to the named file with a Writer
+// configured by any options given, and writes a sidecar file, named by
+// adding ".sha256" to name, in the format of the sha256sum command. The
+// sidecar holds the SHA-256 digest of the file, and that of its data as
+// a flat binary image with gaps filled with fill, listed under name
+// with its extension replaced by ".bin".
+func WriteFileWithDigests(name string, img *Image, fill byte, opts ...WriterOption) error {
+	text, data := sha256.New(), sha256.New()
+	opts = append(append([]WriterOption(nil), opts...), Digests(text, data, fill))
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	hw := NewWriter(f, opts...)
+	err = hw.WriteImage(img)
+	if err == nil {
+		err = hw.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(name)
+	bin := strings.TrimSuffix(base, filepath.Ext(base)) + ".bin"
+	var b bytes.Buffer
+	writeDigestLine(&b, text, base)
+	writeDigestLine(&b, data, bin)
+	return os.WriteFile(name+".sha256", b.Bytes(), 0666)
+}
+
+// writeDigestLine writes the sum of h and name in the format of the
+// sha256sum command.
+func writeDigestLine(w io.Writer, h hash.Hash, name string) {
+	fmt.Fprintf(w, "%x  %s\n", h.Sum(nil), name)
+}
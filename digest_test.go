@@ -0,0 +1,62 @@
+package ihex
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDigests(t *testing.T) {
+	img := &Image{}
+	img.WriteAt([]byte{1, 2}, 0x10)
+	img.WriteAt([]byte{3}, 0x14)
+	var text, data strings.Builder
+	hw := NewWriter(&strings.Builder{}, Digests(&text, &data, 0xee))
+	if err := hw.WriteImage(img); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := hw.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := ":020010000102EB\n:0100140003E8\n:00000001FF\n"; text.String() != want {
+		t.Errorf("expected text\n%s\ngot\n%s", want, text.String())
+	}
+	if want := "\x01\x02\xee\xee\x03"; data.String() != want {
+		t.Errorf("expected data %q, got %q", want, data.String())
+	}
+
+	hw = NewWriter(&strings.Builder{}, Digests(nil, &data, 0xff))
+	hw.WriteData(0x10, []byte{1})
+	if err := hw.WriteData(0x0f, []byte{1}); err == nil {
+		t.Error("expected error for data out of order")
+	}
+}
+
+func TestWriteFileWithDigests(t *testing.T) {
+	img := &Image{}
+	img.WriteAt([]byte{1, 2}, 0)
+	img.WriteAt([]byte{3}, 4)
+	name := filepath.Join(t.TempDir(), "app.hex")
+	if err := WriteFileWithDigests(name, img, 0xff, LowerCase()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	text, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ":020000000102fb\n:0100040003f8\n:00000001ff\n"; string(text) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, text)
+	}
+	sidecar, err := os.ReadFile(name + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%x  app.hex\n%x  app.bin\n",
+		sha256.Sum256(text), sha256.Sum256(img.Bytes(0xff)))
+	if string(sidecar) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, sidecar)
+	}
+}
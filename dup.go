@@ -0,0 +1,97 @@
+package ihex
+
+// A Duplicate is a run of bytes that appears at more than one address.
+type Duplicate struct {
+	Addr uint32 // address of the first occurrence
+	Copy uint32 // address of a later occurrence
+	Len  int
+}
+
+// dupHashBase is the base of the rolling hash used by FindDuplicates.
+const dupHashBase = 1099511628211
+
+// FindDuplicates returns the runs of at least minLen bytes in recs that
+// are repeated at a higher address, such as tables copied by mistake or
+// assets linked twice. Each later occurrence is reported once, extended
+// as far as it matches, against the first occurrence. Runs of a single
+// repeated byte value, such as erased flash, are ignored. Where records
+// overlap, the data of the last one is used.
+func FindDuplicates(recs []Record, minLen int) []Duplicate {
+	if minLen < 1 {
+		minLen = 1
+	}
+	var segs spans
+	for _, r := range recs {
+		segs.add(r)
+	}
+	type pos struct{ seg, off int }
+	seen := make(map[uint64][]pos)
+	var dups []Duplicate
+	pow := uint64(1)
+	for i := 1; i < minLen; i++ {
+		pow *= dupHashBase
+	}
+	for si, seg := range segs {
+		b := seg.Bytes
+		if len(b) < minLen {
+			continue
+		}
+		// same[i] is the length of the run of equal bytes starting at i
+		same := make([]int, len(b))
+		for i := len(b) - 1; i >= 0; i-- {
+			same[i] = 1
+			if i+1 < len(b) && b[i+1] == b[i] {
+				same[i] += same[i+1]
+			}
+		}
+		hash := func(i int) uint64 {
+			var h uint64
+			for _, c := range b[i : i+minLen] {
+				h = h*dupHashBase + uint64(c)
+			}
+			return h
+		}
+		h := hash(0)
+		for i := 0; i+minLen <= len(b); {
+			n := 0
+			var orig pos
+			if same[i] < minLen {
+			Candidates:
+				for _, c := range seen[h] {
+					cb := segs[c.seg].Bytes
+					for n = 0; c.off+n < len(cb) && i+n < len(b) &&
+						cb[c.off+n] == b[i+n]; n++ {
+						if c.seg == si && c.off+n >= i {
+							break
+						}
+					}
+					if n >= minLen {
+						orig = c
+						break Candidates
+					}
+				}
+				if n < minLen {
+					seen[h] = append(seen[h], pos{si, i})
+				}
+			}
+			if n < minLen {
+				if i+minLen < len(b) {
+					h = (h-uint64(b[i])*pow)*dupHashBase +
+						uint64(b[i+minLen])
+				}
+				i++
+				continue
+			}
+			dups = append(dups, Duplicate{
+				Addr: segs[orig.seg].Address + uint32(orig.off),
+				Copy: seg.Address + uint32(i),
+				Len:  n,
+			})
+			i += n
+			if i+minLen <= len(b) {
+				h = hash(i)
+			}
+		}
+	}
+	return dups
+}
@@ -0,0 +1,34 @@
+package ihex
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	table := []byte("0123456789abcdef")
+	recs := []Record{
+		{0x100, append([]byte("xx"), table...)},
+		{0x200, bytes.Repeat([]byte{0xff}, 64)},
+		{0x300, append(append([]byte("yy"), table[:10]...), "zz"...)},
+		{0x400, append(table, table...)},
+	}
+	dups := FindDuplicates(recs, 8)
+	expected := []Duplicate{
+		{0x102, 0x302, 10},
+		{0x102, 0x400, 16},
+		{0x102, 0x410, 16},
+	}
+	if len(dups) != len(expected) {
+		t.Fatal("incorrect duplicates", dups)
+	}
+	for i := range dups {
+		if dups[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], dups[i])
+		}
+	}
+
+	if dups := FindDuplicates(recs, 17); len(dups) != 0 {
+		t.Error("unexpected duplicates", dups)
+	}
+}
@@ -0,0 +1,148 @@
+package ihex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// TranscodeUTF16 makes the Parser accept UTF-16 encoded input, which is
+// detected by its byte order mark or by the NUL bytes that accompany
+// each ASCII character. Input that is not UTF-16 is read unchanged.
+func TranscodeUTF16() Option {
+	return func(p *Parser) {
+		p.utf16 = true
+	}
+}
+
+// checkEncoding examines the first line of input, returning it with any
+// UTF-8 byte order mark removed. It returns nil with p.err set if the
+// line looks like UTF-16.
+func (p *Parser) checkEncoding(b []byte) []byte {
+	if bytes.HasPrefix(b, bomUTF8) {
+		return b[len(bomUTF8):]
+	}
+	if bytes.HasPrefix(b, bomUTF16LE) || bytes.HasPrefix(b, bomUTF16BE) ||
+		utf16Order(b) != nil {
+		p.err = p.makeError("file is UTF-16 encoded")
+		return nil
+	}
+	return b
+}
+
+// utf16Order guesses the byte order of text that starts with ASCII
+// characters encoded as UTF-16, returning nil if it does not. So that
+// binary data is not mistaken for text, up to four code units are
+// checked, and each must hold a printable character or whitespace.
+func utf16Order(b []byte) binary.ByteOrder {
+	n := min(len(b)/2, 4)
+	if n == 0 {
+		return nil
+	}
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		i := 0
+		for i < n && isText(order.Uint16(b[2*i:])) {
+			i++
+		}
+		if i == n {
+			return order
+		}
+	}
+	return nil
+}
+
+// isText reports whether u is a printable ASCII character or whitespace.
+func isText(u uint16) bool {
+	return (u >= ' ' && u < 0x7f) || u == '\t' || u == '\r' || u == '\n'
+}
+
+// A utf16Reader transcodes UTF-16 input to UTF-8.
+type utf16Reader struct {
+	r       *bufio.Reader
+	order   binary.ByteOrder
+	started bool
+	pending []byte
+}
+
+func newUTF16Reader(r io.Reader) *utf16Reader {
+	return &utf16Reader{r: bufio.NewReader(r)}
+}
+
+func (u *utf16Reader) detect() {
+	u.started = true
+	b, _ := u.r.Peek(8)
+	switch {
+	case bytes.HasPrefix(b, bomUTF16LE):
+		u.order = binary.LittleEndian
+		u.r.Discard(2)
+	case bytes.HasPrefix(b, bomUTF16BE):
+		u.order = binary.BigEndian
+		u.r.Discard(2)
+	default:
+		u.order = utf16Order(b)
+	}
+}
+
+func (u *utf16Reader) Read(b []byte) (int, error) {
+	if !u.started {
+		u.detect()
+	}
+	if u.order == nil {
+		return u.r.Read(b)
+	}
+	n := 0
+	for n < len(b) {
+		if len(u.pending) == 0 {
+			r, err := u.readRune()
+			if err != nil {
+				if n > 0 {
+					return n, nil
+				}
+				return 0, err
+			}
+			u.pending = utf8.AppendRune(u.pending, r)
+		}
+		c := copy(b[n:], u.pending)
+		u.pending = u.pending[c:]
+		n += c
+		if u.r.Buffered() == 0 && len(u.pending) == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+func (u *utf16Reader) readRune() (rune, error) {
+	r, err := u.readUnit()
+	if err != nil {
+		return 0, err
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+	r2, err := u.readUnit()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	return utf16.DecodeRune(r, r2), nil
+}
+
+func (u *utf16Reader) readUnit() (rune, error) {
+	var unit [2]byte
+	if _, err := io.ReadFull(u.r, unit[:]); err != nil {
+		return 0, err
+	}
+	return rune(u.order.Uint16(unit[:])), nil
+}
@@ -0,0 +1,151 @@
+package ihex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseTransform returns the Transform described by expr, a pipeline of
+// built-in transforms separated by '|', such as
+//
+//	crop(0x8000, 0x20000) | offset(-0x8000) | fill(0xFF)
+//
+// The transforms are crop(start, end), offset(delta), fill(value),
+// split(n), and dedupe(), which work like the functions of the same
+// names. Arguments are integers in Go syntax, with an optional sign.
+func ParseTransform(expr string) (Transform, error) {
+	x := &exprParser{s: expr}
+	var ts []Transform
+	for {
+		t, err := x.parseCall()
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+		if x.skipSpace(); x.pos == len(x.s) {
+			break
+		}
+		if err := x.expect('|'); err != nil {
+			return nil, err
+		}
+	}
+	if len(ts) == 1 {
+		return ts[0], nil
+	}
+	return Chain(ts...), nil
+}
+
+// exprTransforms holds the number of arguments taken by each transform,
+// and the range allowed for each argument.
+var exprTransforms = map[string][][2]int64{
+	"crop":   {{0, 0xffffffff}, {0, 0xffffffff}},
+	"offset": {{-0xffffffff, 0xffffffff}},
+	"fill":   {{0, 0xff}},
+	"split":  {{1, 0xffffffff}},
+	"dedupe": {},
+}
+
+type exprParser struct {
+	s   string
+	pos int
+}
+
+func (x *exprParser) errorf(pos int, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return fmt.Errorf("transform: column %d: %s", pos+1, msg)
+}
+
+func (x *exprParser) skipSpace() {
+	for x.pos < len(x.s) && strings.IndexByte(" \t\r\n", x.s[x.pos]) >= 0 {
+		x.pos++
+	}
+}
+
+// peek reports whether the next non-space character is c.
+func (x *exprParser) peek(c byte) bool {
+	x.skipSpace()
+	return x.pos < len(x.s) && x.s[x.pos] == c
+}
+
+func (x *exprParser) expect(c byte) error {
+	if !x.peek(c) {
+		return x.errorf(x.pos, "expected %q", c)
+	}
+	x.pos++
+	return nil
+}
+
+// scan returns the run of characters at the current position for which
+// ok is true.
+func (x *exprParser) scan(ok func(c byte) bool) string {
+	x.skipSpace()
+	start := x.pos
+	for x.pos < len(x.s) && ok(x.s[x.pos]) {
+		x.pos++
+	}
+	return x.s[start:x.pos]
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isNumberChar(c byte) bool {
+	return isNameChar(c) || c >= '0' && c <= '9' || c == '+' || c == '-'
+}
+
+func (x *exprParser) parseCall() (Transform, error) {
+	x.skipSpace()
+	pos := x.pos
+	name := x.scan(isNameChar)
+	if name == "" {
+		return nil, x.errorf(pos, "expected transform name")
+	}
+	ranges, ok := exprTransforms[name]
+	if !ok {
+		return nil, x.errorf(pos, "unknown transform %q", name)
+	}
+	if err := x.expect('('); err != nil {
+		return nil, err
+	}
+	var args []int64
+	for !x.peek(')') || len(args) > 0 {
+		if len(args) > 0 {
+			if err := x.expect(','); err != nil {
+				return nil, err
+			}
+		}
+		x.skipSpace()
+		argPos := x.pos
+		tok := x.scan(isNumberChar)
+		n, err := strconv.ParseInt(tok, 0, 64)
+		if err != nil {
+			return nil, x.errorf(argPos, "invalid number %q", tok)
+		}
+		if i := len(args); i < len(ranges) &&
+			(n < ranges[i][0] || n > ranges[i][1]) {
+			return nil, x.errorf(argPos, "%s argument out of range", name)
+		}
+		args = append(args, n)
+		if x.peek(')') {
+			break
+		}
+	}
+	x.pos++
+	if len(args) != len(ranges) {
+		return nil, x.errorf(pos, "%s takes %d arguments, not %d",
+			name, len(ranges), len(args))
+	}
+	switch name {
+	case "crop":
+		return Crop(uint32(args[0]), uint32(args[1])), nil
+	case "offset":
+		return Offset(args[0]), nil
+	case "fill":
+		return Fill(byte(args[0])), nil
+	case "split":
+		return Split(uint32(args[0])), nil
+	}
+	return Dedupe(), nil
+}
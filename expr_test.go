@@ -0,0 +1,54 @@
+package ihex
+
+import "testing"
+
+func TestParseTransform(t *testing.T) {
+	tr, err := ParseTransform(
+		" crop(0x8002, 0x8012) | offset(-0x8000)|split( 4 )|dedupe() ")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	recs := []Record{
+		{0x8000, []byte{1, 2, 3, 4}},
+		{0x8010, []byte{5, 6, 7, 8}},
+		{0x8003, []byte{4}},
+	}
+	checkRecords(t, runTransform(t, tr, recs), []Record{
+		{0x02, []byte{3, 4}},
+		{0x10, []byte{5, 6}},
+	})
+
+	tr, err = ParseTransform("fill(255)")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, runTransform(t, tr, recs[:2]), []Record{
+		{0x8000, []byte{1, 2, 3, 4}},
+		{0x8004, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{0x8010, []byte{5, 6, 7, 8}},
+	})
+}
+
+func TestParseTransformErrors(t *testing.T) {
+	var cases = [][]string{
+		{"", "transform: column 1: expected transform name"},
+		{"crop(1, 2) offset(3)", "transform: column 12: expected '|'"},
+		{"shift(1)", `transform: column 1: unknown transform "shift"`},
+		{"fill", "transform: column 5: expected '('"},
+		{"fill(256)", "transform: column 6: fill argument out of range"},
+		{"fill(0xGG)", `transform: column 6: invalid number "0xGG"`},
+		{"fill(1 2)", "transform: column 8: expected ','"},
+		{"crop(1)", "transform: column 1: crop takes 2 arguments, not 1"},
+		{"dedupe(1)", "transform: column 1: dedupe takes 0 arguments, not 1"},
+		{"split(0)", "transform: column 7: split argument out of range"},
+		{"offset(1", `transform: column 9: expected ','`},
+		{"crop(1,2) |", "transform: column 12: expected transform name"},
+	}
+	for _, c := range cases {
+		_, err := ParseTransform(c[0])
+		if err == nil || err.Error() != c[1] {
+			t.Errorf("%q: expected %q, got %v", c[0], c[1], err)
+		}
+	}
+}
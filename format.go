@@ -0,0 +1,72 @@
+package ihex
+
+import "fmt"
+
+// A FileFormat is a variant of the Intel HEX format, defined by the
+// record types that it uses and the size of its address space.
+type FileFormat int
+
+const (
+	I8HEX  FileFormat = iota + 1 // types 0 and 1; 16-bit addresses
+	I16HEX                       // types 0 to 3; 20-bit segmented addresses
+	I32HEX                       // types 0, 1, 4, and 5; 32-bit addresses
+)
+
+var formatNames = map[FileFormat]string{
+	I8HEX:  "I8HEX",
+	I16HEX: "I16HEX",
+	I32HEX: "I32HEX",
+}
+
+func (f FileFormat) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("FileFormat(%d)", int(f))
+}
+
+// formatTypes holds the record types allowed by each FileFormat, as a
+// bit set.
+var formatTypes = map[FileFormat]uint{
+	I8HEX:  1<<0 | 1<<1,
+	I16HEX: 1<<0 | 1<<1 | 1<<2 | 1<<3,
+	I32HEX: 1<<0 | 1<<1 | 1<<4 | 1<<5,
+}
+
+// Format makes the Parser stop with an error at any record that is not
+// allowed in files of format f: a record of a type that f does not use,
+// or a data record with an address outside of its address space.
+func Format(f FileFormat) Option {
+	return func(p *Parser) {
+		p.format = f
+	}
+}
+
+// checkFormat checks a record header against the FileFormat, if any.
+func (p *Parser) checkFormat(rectyp, reclen byte, offset uint16) {
+	if p.err != nil || p.format == 0 {
+		return
+	}
+	if rectyp >= 32 || formatTypes[p.format]&(1<<rectyp) == 0 {
+		p.err = p.makeError(fmt.Sprintf(
+			"record type %02X not allowed in %v", rectyp, p.format))
+		return
+	}
+	if rectyp != 0 || reclen == 0 {
+		return
+	}
+	last := int(offset) + int(reclen) - 1
+	switch p.format {
+	case I8HEX:
+		if last > 0xffff {
+			p.err = p.makeError(fmt.Sprintf(
+				"data past end of 64K address space in %v", p.format))
+		}
+	case I16HEX:
+		// data that wraps around reaches the end of the segment
+		if p.sba+uint32(min(last, 0xffff)) > 0xfffff {
+			p.err = p.makeError(fmt.Sprintf(
+				"data past end of 1M address space in %v", p.format))
+		}
+	}
+}
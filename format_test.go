@@ -0,0 +1,41 @@
+package ihex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format FileFormat
+		input  string
+		err    string
+	}{
+		{I8HEX, ":01FFF000010F\n:00000001FF\n", ""},
+		{I8HEX, ":020000040001F9\n:00000001FF\n",
+			"line 1: record type 04 not allowed in I8HEX"},
+		{I8HEX, ":02FFFF000102FD\n:00000001FF\n",
+			"line 1: data past end of 64K address space in I8HEX"},
+		{I16HEX, ":02000002F0000C\n:02FFFF000102FD\n:00000001FF\n", ""},
+		{I16HEX, ":02000002F0010B\n:01FFF000010F\n:00000001FF\n",
+			"line 2: data past end of 1M address space in I16HEX"},
+		{I16HEX, ":020000040001F9\n:00000001FF\n",
+			"line 1: record type 04 not allowed in I16HEX"},
+		{I32HEX, ":020000040001F9\n:01FFF000010F\n:00000001FF\n", ""},
+		{I32HEX, ":02000002F0000C\n:00000001FF\n",
+			"line 1: record type 02 not allowed in I32HEX"},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input), Format(tt.format))
+		for p.Parse() {
+		}
+		err := ""
+		if p.Err() != nil {
+			err = p.Err().Error()
+		}
+		if err != tt.err {
+			t.Errorf("%v %q: expected error %q, got %q",
+				tt.format, tt.input, tt.err, err)
+		}
+	}
+}
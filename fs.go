@@ -0,0 +1,81 @@
+package ihex
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// hexExts are the file name extensions recognized by ReadFS.
+var hexExts = []string{".hex", ".ihex", ".ihx"}
+
+// ReadFS walks fsys, such as an update package opened with
+// archive/zip, and parses every Intel HEX file that it finds, judged by
+// a file name extension of .hex, .ihex, or .ihx in any case. It returns
+// the data records of each file keyed by its path within fsys. Each file
+// is parsed by a Parser configured by any options given. ReadFS in
+// package srec also reads Motorola S-record files.
+func ReadFS(fsys fs.FS, opts ...Option) (map[string][]Record, error) {
+	files := make(map[string][]Record)
+	err := walkHexFiles(fsys, func(name string, b []byte) error {
+		recs, err := ParseRecords(b, opts...)
+		if err != nil {
+			return err
+		}
+		files[name] = recs
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ReadImagesFS is like ReadFS, but returns an Image of each file,
+// including its start address.
+func ReadImagesFS(fsys fs.FS, opts ...Option) (map[string]*Image, error) {
+	files := make(map[string]*Image)
+	err := walkHexFiles(fsys, func(name string, b []byte) error {
+		img, err := ReadImage(bytes.NewReader(b), opts...)
+		if err != nil {
+			return err
+		}
+		files[name] = img
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// walkHexFiles calls fn with the path and contents of each Intel HEX
+// file in fsys, adding the path to any error that fn returns.
+func walkHexFiles(fsys fs.FS, fn func(name string, b []byte) error) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry,
+		err error) error {
+		if err != nil || d.IsDir() || !isHexFile(name) {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if err := fn(name, b); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func isHexFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, hexExt := range hexExts {
+		if ext == hexExt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package ihex
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadFS(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"manifest.json": "{}",
+		"boot/boot.HEX": ":0B0010006164647265737320676170A7\n:00000001FF\n",
+		"app.ihx":       ":00000001FF\n",
+	}
+	for name, body := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFS(zr)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(got) != 2 || len(got["app.ihx"]) != 0 {
+		t.Error("incorrect files", got)
+	}
+	checkRecords(t, got["boot/boot.HEX"], []Record{{0x10, []byte("address gap")}})
+
+	imgs, err := ReadImagesFS(zr)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(imgs) != 2 || imgs["app.ihx"].Size() != 0 {
+		t.Error("incorrect files", imgs)
+	}
+	checkRecords(t, imgs["boot/boot.HEX"].Segments(),
+		[]Record{{0x10, []byte("address gap")}})
+
+	fsys := fstest.MapFS{"bad.hex": {Data: []byte(":00000001FE")}}
+	_, err = ReadFS(fsys)
+	if err == nil || err.Error() != "bad.hex: line 1: invalid checksum: "+
+		"stored FE, computed FF" {
+		t.Error("missed parse error", err)
+	}
+	if _, err := ReadImagesFS(fsys); err == nil {
+		t.Error("missed parse error")
+	}
+}
@@ -0,0 +1,30 @@
+package ihex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// ExportGDB writes a GDB command script to w that stores each byte of
+// the data records read by p into the memory of a debugging target, for
+// use with GDB's source command when no flash programmer is available.
+// Each byte is written by its own set command, so the script does not
+// depend on the target's word size or byte order.
+func ExportGDB(w io.Writer, p *Parser) error {
+	bw := bufio.NewWriter(w)
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		data := p.Data()
+		for i, b := range data.Bytes {
+			addr := data.Address + uint32(i)
+			fmt.Fprintf(bw, "set {unsigned char}0x%08x = 0x%02x\n", addr, b)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,24 @@
+package ihex
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExportGDB(t *testing.T) {
+	records := `
+:020000040800F2
+:02FFFE000102FE
+:00000001FF
+`
+	var buf bytes.Buffer
+	if err := ExportGDB(&buf, ParseString(records)); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	expected := `set {unsigned char}0x0800fffe = 0x01
+set {unsigned char}0x0800ffff = 0x02
+`
+	if buf.String() != expected {
+		t.Errorf("incorrect script:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,204 @@
+// Package ihexhttp provides an http.Handler that inspects and converts
+// uploaded Intel HEX files.
+package ihexhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/edmccard/ihex"
+	"github.com/edmccard/ihex/srec"
+)
+
+// DefaultMaxSize is the largest upload accepted by a Handler whose
+// MaxSize is zero.
+const DefaultMaxSize = 32 << 20
+
+// A Handler serves POST requests carrying an Intel HEX file, either as
+// the request body or as the "file" field of a multipart form. The
+// "format" query parameter selects the response:
+//
+//   - json (the default): a JSON Report on the file
+//   - bin: the file converted to a flat binary image, with gaps filled
+//     with the byte given in hex by the "fill" parameter (default FF)
+//   - srec: the file converted to Motorola S-record format
+//
+// A file that cannot be converted gets a 422 response holding the
+// error.
+type Handler struct {
+	MaxSize int64         // largest upload accepted, in bytes
+	Options []ihex.Option // options for the Parser
+}
+
+// A Report describes an uploaded file. Valid is true if the file parsed
+// without error; otherwise Error describes the first error found.
+type Report struct {
+	Valid      bool          `json:"valid"`
+	Error      string        `json:"error,omitempty"`
+	ErrorLine  int           `json:"error_line,omitempty"`
+	Warnings   []Warning     `json:"warnings"`
+	Records    int           `json:"records"`
+	Bytes      int           `json:"bytes"`
+	MinAddress *uint32       `json:"min_address,omitempty"`
+	MaxAddress *uint32       `json:"max_address,omitempty"`
+	Start      *StartAddress `json:"start,omitempty"`
+}
+
+// A Warning is a problem that the Parser tolerated.
+type Warning struct {
+	Line int    `json:"line"`
+	Msg  string `json:"msg"`
+}
+
+// A StartAddress holds the start address from a type 3 or type 5
+// record.
+type StartAddress struct {
+	CS  *uint16 `json:"cs,omitempty"`
+	IP  *uint16 `json:"ip,omitempty"`
+	EIP *uint32 `json:"eip,omitempty"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	maxSize := h.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, maxSize)
+	body, err := upload(req)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	query := req.URL.Query()
+	var (
+		out         bytes.Buffer
+		contentType string
+	)
+	switch format := query.Get("format"); format {
+	case "", "json":
+		report := Inspect(ihex.ParseBytes(body, h.Options...))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	case "bin":
+		fill := uint64(0xff)
+		if s := query.Get("fill"); s != "" {
+			if fill, err = strconv.ParseUint(s, 16, 8); err != nil {
+				http.Error(w, "invalid fill byte", http.StatusBadRequest)
+				return
+			}
+		}
+		contentType = "application/octet-stream"
+		err = ihex.ToBinary(bytes.NewReader(body), &out,
+			ihex.PadByte(byte(fill)), ihex.ParserOptions(h.Options...))
+	case "srec":
+		contentType = "text/plain; charset=utf-8"
+		err = toSRecord(ihex.ParseBytes(body, h.Options...), &out)
+	default:
+		http.Error(w, "unknown format "+strconv.Quote(format),
+			http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(out.Bytes())
+}
+
+// toSRecord writes the data records read by p, and any start address
+// from a type 5 record, to w in Motorola S-record format.
+func toSRecord(p *ihex.Parser, w io.Writer) error {
+	var img ihex.Image
+	if err := img.Load(p); err != nil {
+		return err
+	}
+	sw := srec.NewWriter(w)
+	for _, seg := range img.Segments() {
+		if err := sw.WriteData(seg.Address, seg.Bytes); err != nil {
+			return err
+		}
+	}
+	if eip, ok := p.EIP(); ok {
+		if err := sw.WriteStart(eip); err != nil {
+			return err
+		}
+	}
+	return sw.Close()
+}
+
+// upload returns the file from the body of req.
+func upload(req *http.Request) ([]byte, error) {
+	f, _, err := req.FormFile("file")
+	if err == http.ErrNotMultipart {
+		return io.ReadAll(req.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Inspect reads all of the data records from p, and returns a Report on
+// them.
+func Inspect(p *ihex.Parser) Report {
+	var r Report
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		data := p.Data()
+		if len(data.Bytes) == 0 {
+			continue
+		}
+		first := data.Address
+		last := data.Address + uint32(len(data.Bytes)-1)
+		if r.MinAddress == nil || first < *r.MinAddress {
+			r.MinAddress = &first
+		}
+		if r.MaxAddress == nil || last > *r.MaxAddress {
+			r.MaxAddress = &last
+		}
+	}
+	m := p.Metrics()
+	r.Records, r.Bytes = m.Records, m.Bytes
+	r.Warnings = []Warning{}
+	for _, warning := range p.Warnings() {
+		r.Warnings = append(r.Warnings, Warning(warning))
+	}
+	if err := p.Err(); err != nil {
+		r.Error = err.Error()
+		var perr ihex.ParseError
+		if errors.As(err, &perr) {
+			r.Error, r.ErrorLine = perr.Msg, perr.Line
+		}
+		return r
+	}
+	r.Valid = true
+	if cs, ip, ok := p.CSIP(); ok {
+		r.Start = &StartAddress{CS: &cs, IP: &ip}
+	}
+	if eip, ok := p.EIP(); ok {
+		if r.Start == nil {
+			r.Start = &StartAddress{}
+		}
+		r.Start.EIP = &eip
+	}
+	return r
+}
@@ -0,0 +1,108 @@
+package ihexhttp
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/edmccard/ihex"
+	"github.com/edmccard/ihex/srec"
+)
+
+const records = `banner
+:020000021200EA
+:0B0010006164647265737320676170A7
+:0400000300001234B3
+:00000001FF
+`
+
+func TestHandler(t *testing.T) {
+	h := &Handler{Options: []ihex.Option{ihex.NonRecordLines(ihex.WarnLines)}}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(records))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	expected := `{"valid":true,"warnings":[{"line":1,"msg":"missing record mark"}],` +
+		`"records":4,"bytes":11,"min_address":73744,"max_address":73754,` +
+		`"start":{"cs":0,"ip":4660}}` + "\n"
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Errorf("incorrect response %d %s", w.Code, w.Body)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "fw.hex")
+	fw.Write([]byte(":00000001FE\n"))
+	mw.Close()
+	req = httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	expected = `{"valid":false,"error":"invalid checksum: stored FE, ` +
+		`computed FF","error_line":1,"warnings":[],"records":0,"bytes":0}` +
+		"\n"
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Errorf("incorrect response %d %s", w.Code, w.Body)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := &Handler{MaxSize: 16}
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error("expected 405, got", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(records))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Error("expected 413, got", w.Code)
+	}
+}
+
+func TestHandlerConvert(t *testing.T) {
+	h := &Handler{Options: []ihex.Option{ihex.NonRecordLines(ihex.SkipLines)}}
+	post := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/?"+query, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("format=bin", records)
+	if w.Code != http.StatusOK || w.Body.String() != "address gap" ||
+		w.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("incorrect binary response %d %q", w.Code, w.Body)
+	}
+
+	var want bytes.Buffer
+	sw := srec.NewWriter(&want)
+	sw.WriteData(0x12010, []byte("address gap"))
+	sw.Close()
+	w = post("format=srec", records)
+	if w.Code != http.StatusOK || w.Body.String() != want.String() {
+		t.Errorf("incorrect S-record response %d %q", w.Code, w.Body)
+	}
+
+	gap := ":0100000041BE\n:0100020042BB\n:00000001FF\n"
+	if w = post("format=bin&fill=00", gap); w.Body.String() != "A\x00B" {
+		t.Errorf("incorrect fill %q", w.Body)
+	}
+
+	for query, code := range map[string]int{
+		"format=bin&fill=XYZ": http.StatusBadRequest,
+		"format=pdf":          http.StatusBadRequest,
+	} {
+		if w = post(query, records); w.Code != code {
+			t.Errorf("%s: expected %d, got %d", query, code, w.Code)
+		}
+	}
+	if w = post("format=srec", ":00000001FE\n"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for invalid file, got %d", w.Code)
+	}
+}
@@ -0,0 +1,152 @@
+// Package ihextest generates Intel HEX inputs, both valid and
+// systematically invalid, for table-driven tests and as seeds for fuzz
+// tests of code that parses HEX files, and random Images for
+// property-based tests.
+package ihextest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// A Case is a generated input.
+type Case struct {
+	Name  string
+	Input []byte
+	Valid bool // whether Input is a valid Intel HEX file
+}
+
+// Record returns the text of a record with the given type, load offset,
+// and data, with a correct checksum and without a line terminator.
+func Record(rectyp byte, offset uint16, data []byte) string {
+	b := append([]byte{byte(len(data)), byte(offset >> 8), byte(offset),
+		rectyp}, data...)
+	var sum byte
+	for _, c := range b {
+		sum += c
+	}
+	b = append(b, -sum)
+	return fmt.Sprintf(":%X", b)
+}
+
+// End is the text of an end of file record.
+const End = ":00000001FF"
+
+// File returns the text of a file holding the given records, each
+// followed by "\n", with an end record added at the end.
+func File(records ...string) []byte {
+	return []byte(strings.Join(append(records, End), "\n") + "\n")
+}
+
+func seq(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*7 + 1)
+	}
+	return b
+}
+
+// Valid returns valid inputs that exercise each record type and the
+// address calculations that depend on them.
+func Valid() []Case {
+	return []Case{
+		{"end only", File(), true},
+		{"data", File(Record(0, 0x0010, seq(16)), Record(0, 0x0020, seq(16))),
+			true},
+		{"max length", File(Record(0, 0, seq(255))), true},
+		{"empty data", File(Record(0, 0x1234, nil)), true},
+		{"segment", File(Record(2, 0, []byte{0x12, 0x00}),
+			Record(0, 0x0010, seq(8))), true},
+		{"segment wrap", File(Record(2, 0, []byte{0x12, 0x00}),
+			Record(0, 0xfffc, seq(8))), true},
+		{"linear", File(Record(4, 0, []byte{0x08, 0x00}),
+			Record(0, 0xfff8, seq(8)), Record(4, 0, []byte{0x08, 0x01}),
+			Record(0, 0x0000, seq(8))), true},
+		{"start segment", File(Record(3, 0, []byte{0x12, 0x34, 0x56, 0x78})),
+			true},
+		{"start linear", File(Record(5, 0, []byte{0x08, 0x00, 0x01, 0x01})),
+			true},
+		{"lower case", bytes.ToLower(File(Record(0, 0xabcd, seq(4)))), true},
+		{"crlf", bytes.ReplaceAll(File(Record(0, 0, seq(4))),
+			[]byte("\n"), []byte("\r\n")), true},
+		{"blank lines", []byte("\n" + Record(0, 0, seq(4)) + "\n\n" + End),
+			true},
+	}
+}
+
+// Invalid returns invalid inputs, made by breaking each record of a
+// valid input in turn in each of several ways: a wrong checksum,
+// truncation inside each field, a wild record type, a wrong length for
+// the record type, a bad hex digit, trailing characters, and a missing
+// record mark. It also includes files with a missing end record, data
+// after the end record, and binary content.
+func Invalid() []Case {
+	records := []string{
+		Record(4, 0, []byte{0x00, 0x01}),
+		Record(0, 0x0100, seq(16)),
+		Record(5, 0, []byte{0x00, 0x01, 0x00, 0x00}),
+	}
+	var cases []Case
+	broken := func(name string, i int, rec string) {
+		recs := append([]string(nil), records...)
+		recs[i] = rec
+		cases = append(cases, Case{
+			Name:  fmt.Sprintf("%s in record %d", name, i),
+			Input: File(recs...),
+		})
+	}
+	for i, rec := range records {
+		last := rec[len(rec)-2:]
+		bad := fmt.Sprintf("%02X", 0xff^hexByte(last))
+		broken("bad checksum", i, rec[:len(rec)-2]+bad)
+		for _, n := range []int{1, 2, 3, 5, 7, 8, 9, len(rec) - 1} {
+			broken(fmt.Sprintf("truncated at %d", n), i, rec[:n])
+		}
+		for _, typ := range []byte{0x06, 0x0f, 0x80, 0xff} {
+			broken(fmt.Sprintf("type %02X", typ), i, retype(rec, typ))
+		}
+		broken("bad hex digit", i, rec[:3]+"G"+rec[4:])
+		broken("trailing characters", i, rec+"00")
+		broken("missing record mark", i, rec[1:])
+	}
+	broken("wrong length", 0, Record(4, 0, []byte{0x00}))
+	broken("wrong length", 2, Record(5, 0, []byte{0x00, 0x01}))
+	cases = append(cases,
+		Case{Name: "missing end", Input: []byte(records[1] + "\n")},
+		Case{Name: "data after end",
+			Input: append(File(records...), records[1]+"\n"...)},
+		Case{Name: "binary", Input: []byte("\x0c\x94\x34\x00\x00\x00\n")},
+		Case{Name: "empty", Input: nil},
+	)
+	return cases
+}
+
+// Corpus returns the cases from Valid followed by those from Invalid.
+func Corpus() []Case {
+	return append(Valid(), Invalid()...)
+}
+
+// AddSeeds adds the input of each case in Corpus to the seed corpus of
+// f, for a fuzz target taking a single []byte argument.
+func AddSeeds(f *testing.F) {
+	for _, c := range Corpus() {
+		f.Add(c.Input)
+	}
+}
+
+func hexByte(s string) byte {
+	var b byte
+	fmt.Sscanf(s, "%02X", &b)
+	return b
+}
+
+// retype returns rec with its type changed to typ, keeping the checksum
+// correct so that only the type is wrong.
+func retype(rec string, typ byte) string {
+	var b []byte
+	fmt.Sscanf(rec[1:], "%X", &b)
+	b[3] = typ
+	return Record(typ, uint16(b[1])<<8|uint16(b[2]), b[4:len(b)-1])
+}
@@ -0,0 +1,139 @@
+package ihextest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/edmccard/ihex"
+)
+
+func TestCorpus(t *testing.T) {
+	for _, c := range Corpus() {
+		p := ihex.ParseBytes(c.Input)
+		for p.Parse() {
+		}
+		if c.Valid && p.Err() != nil {
+			t.Errorf("%s: unexpected error %v", c.Name, p.Err())
+		}
+		if !c.Valid && p.Err() == nil {
+			t.Errorf("%s: missed error", c.Name)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	rec := Record(0, 0x0010, []byte("address gap"))
+	if rec != ":0B0010006164647265737320676170A7" {
+		t.Error("incorrect record", rec)
+	}
+}
+
+// recorder is a testing.TB that records the errors reported to it.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+// passThrough copies each line read from r to w, with only the data
+// records changed by fn.
+func passThrough(r io.Reader, w io.Writer, fn func([]byte) []byte) error {
+	p := ihex.NewParser(r, ihex.Tee())
+	for p.Parse() {
+		line := p.Line()
+		if p.HasData() && fn != nil {
+			line = fn(line)
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	for _, c := range Valid() {
+		CheckRoundTrip(t, c.Input)
+		CheckRoundTrip(t, c.Input, ihex.RecordLength(5), ihex.LowerCase())
+	}
+
+	r := &recorder{TB: t}
+	CheckEquivalent(r, File(Record(0, 0, []byte{1, 2}), Record(5, 0, []byte{0, 0, 1, 0})),
+		File(Record(0, 0, []byte{1, 3})))
+	if len(r.errs) != 2 || r.errs[0] != "data changed at 00000001-00000001" ||
+		r.errs[1] != "start address differs" {
+		t.Errorf("incorrect errors %q", r.errs)
+	}
+}
+
+func TestCheckPreserved(t *testing.T) {
+	input := []byte(Record(0, 0x10, seq(4)) + "\r\n" + End + "\n")
+	CheckPreserved(t, input, func(r io.Reader, w io.Writer) error {
+		return passThrough(r, w, nil)
+	})
+
+	rec := &recorder{TB: t}
+	CheckPreserved(rec, input, func(r io.Reader, w io.Writer) error {
+		return passThrough(r, w, func(line []byte) []byte {
+			return append([]byte(nil), line[:len(line)-2]...)
+		})
+	})
+	if len(rec.errs) != 1 || rec.errs[0] !=
+		"output differs from input at offset 19 (got 31 bytes, want 33)" {
+		t.Errorf("incorrect errors %q", rec.errs)
+	}
+}
+
+func FuzzParser(f *testing.F) {
+	AddSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		p := ihex.ParseBytes(b)
+		for p.Parse() {
+		}
+	})
+}
+
+func TestRandomImage(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	c := ImageConfig{Segments: 20, MaxSegment: 16, Base: 0x1000, Span: 0x100}
+	for i := 0; i < 100; i++ {
+		img := RandomImage(r, c)
+		for _, seg := range img.Segments() {
+			if seg.Address < 0x1000 ||
+				uint64(seg.Address)+uint64(len(seg.Bytes)) > 0x1100 {
+				t.Fatalf("segment %08X+%d outside of range",
+					seg.Address, len(seg.Bytes))
+			}
+		}
+	}
+	img := RandomImage(r, ImageConfig{Base: 0xfffffff0, MaxSegment: 64})
+	for _, seg := range img.Segments() {
+		if uint64(seg.Address)+uint64(len(seg.Bytes)) > 1<<32 {
+			t.Fatalf("segment %08X+%d past end of address space",
+				seg.Address, len(seg.Bytes))
+		}
+	}
+
+	// any Image survives being written and read back
+	roundTrip := func(q QuickImage) bool {
+		var b bytes.Buffer
+		w := ihex.NewWriter(&b)
+		if w.WriteImage(q.Image) != nil || w.Close() != nil {
+			return false
+		}
+		img, err := ihex.ReadImage(&b)
+		return err == nil && len(img.Diff(q.Image)) == 0
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,62 @@
+package ihextest
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/edmccard/ihex"
+)
+
+// An ImageConfig sets the shape of the Images made by RandomImage. A
+// zero field takes its default.
+type ImageConfig struct {
+	Segments   int    // most writes of data; the default is 8
+	MaxSegment int    // most bytes in each write; the default is 256
+	Base       uint32 // lowest address of data
+	// Span is the size of the range of addresses, which by default is
+	// the rest of the 32-bit address space above Base.
+	Span uint64
+}
+
+// RandomImage returns an Image holding random data written at random
+// addresses, as configured by c. Writes can overlap or touch, so the
+// Image can have fewer segments than there were writes. It can be used
+// with property-based testing tools that supply a *rand.Rand.
+func RandomImage(r *rand.Rand, c ImageConfig) *ihex.Image {
+	if c.Segments <= 0 {
+		c.Segments = 8
+	}
+	if c.MaxSegment <= 0 {
+		c.MaxSegment = 256
+	}
+	if room := 1<<32 - uint64(c.Base); c.Span == 0 || c.Span > room {
+		c.Span = room
+	}
+	img := &ihex.Image{}
+	for n := r.Intn(c.Segments + 1); n > 0; n-- {
+		size := 1 + r.Intn(c.MaxSegment)
+		if uint64(size) > c.Span {
+			size = int(c.Span)
+		}
+		addr := uint64(c.Base) + uint64(r.Int63n(int64(c.Span)-int64(size)+1))
+		b := make([]byte, size)
+		r.Read(b)
+		img.WriteAt(b, int64(addr))
+	}
+	if r.Intn(2) == 0 {
+		img.SetStartLinear(r.Uint32())
+	}
+	return img
+}
+
+// A QuickImage is an Image that can be generated by testing/quick, with
+// the default ImageConfig, as an argument of a property function.
+type QuickImage struct {
+	*ihex.Image
+}
+
+// Generate implements quick.Generator, with up to size writes of data.
+func (QuickImage) Generate(r *rand.Rand, size int) reflect.Value {
+	img := RandomImage(r, ImageConfig{Segments: size})
+	return reflect.ValueOf(QuickImage{img})
+}
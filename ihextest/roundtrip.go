@@ -0,0 +1,87 @@
+package ihextest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/edmccard/ihex"
+)
+
+// CheckRoundTrip parses input, writes its data and start address with
+// an ihex.Writer configured by opts, and reports an error through t
+// unless the result is equivalent to input, as by CheckEquivalent. It
+// returns the text that was written.
+func CheckRoundTrip(t testing.TB, input []byte, opts ...ihex.WriterOption) []byte {
+	t.Helper()
+	img, err := ihex.ReadImage(bytes.NewReader(input))
+	if err != nil {
+		t.Errorf("parsing input: %v", err)
+		return nil
+	}
+	var b bytes.Buffer
+	w := ihex.NewWriter(&b, opts...)
+	if err := w.WriteImage(img); err != nil {
+		t.Errorf("writing: %v", err)
+		return nil
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("writing: %v", err)
+		return nil
+	}
+	CheckEquivalent(t, b.Bytes(), input)
+	return b.Bytes()
+}
+
+// CheckEquivalent reports an error through t unless got and want are
+// Intel HEX files holding the same data and start address, however
+// their records are laid out. In the differences reported, A is got
+// and B is want.
+func CheckEquivalent(t testing.TB, got, want []byte) {
+	t.Helper()
+	gotImg, err := ihex.ReadImage(bytes.NewReader(got))
+	if err != nil {
+		t.Errorf("parsing result: %v", err)
+		return
+	}
+	wantImg, err := ihex.ReadImage(bytes.NewReader(want))
+	if err != nil {
+		t.Errorf("parsing expected result: %v", err)
+		return
+	}
+	for _, d := range gotImg.Diff(wantImg) {
+		t.Errorf("data %s at %08X-%08X", d.Kind, d.Address,
+			uint64(d.Address)+uint64(d.Len)-1)
+	}
+	gotEIP, gotLinear := gotImg.EIP()
+	wantEIP, wantLinear := wantImg.EIP()
+	gotCS, gotIP, gotSeg := gotImg.CSIP()
+	wantCS, wantIP, wantSeg := wantImg.CSIP()
+	if gotEIP != wantEIP || gotLinear != wantLinear ||
+		gotCS != wantCS || gotIP != wantIP || gotSeg != wantSeg {
+		t.Errorf("start address differs")
+	}
+}
+
+// CheckPreserved runs filter on input and reports an error through t
+// unless the output is identical to input, byte for byte, as it should
+// be for a filter in a mode that preserves its input, such as one
+// built on a Parser with the Tee option that writes each line as read.
+func CheckPreserved(t testing.TB, input []byte, filter func(r io.Reader, w io.Writer) error) {
+	t.Helper()
+	var b bytes.Buffer
+	if err := filter(bytes.NewReader(input), &b); err != nil {
+		t.Errorf("filter: %v", err)
+		return
+	}
+	got := b.Bytes()
+	if bytes.Equal(got, input) {
+		return
+	}
+	i := 0
+	for i < min(len(got), len(input)) && got[i] == input[i] {
+		i++
+	}
+	t.Errorf("output differs from input at offset %d (got %d bytes, want %d)",
+		i, len(got), len(input))
+}
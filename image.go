@@ -0,0 +1,215 @@
+package ihex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// An Image holds data as sparse memory: a set of contiguous segments,
+// each holding the bytes for a range of addresses. Where data is
+// written more than once to the same address, the last write wins. An
+// Image can also hold a start address, like that of a record of type 3
+// or 5. The zero value is an empty Image ready to use.
+type Image struct {
+	segs  spans
+	start *startAddr
+}
+
+// ReadImage returns an Image holding the data records read from r by a
+// Parser configured with any options given.
+func ReadImage(r io.Reader, opts ...Option) (*Image, error) {
+	img := &Image{}
+	if err := img.Load(NewParser(r, opts...)); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Load adds the data records read by p to the Image, and sets its start
+// address if p read a record of type 3 or 5, preferring type 5 if it
+// read both. It returns any error from p.
+func (img *Image) Load(p *Parser) error {
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		img.segs.add(p.Data())
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+	if start := readStart(p); start != nil {
+		img.start = start
+	}
+	return nil
+}
+
+// SetStartLinear sets the start address of the Image to eip, as held by
+// a start linear address record (type 5).
+func (img *Image) SetStartLinear(eip uint32) {
+	img.start = &startAddr{linear: true, eip: eip}
+}
+
+// SetStartSegment sets the start address of the Image to cs and ip, as
+// held by a start segment address record (type 3).
+func (img *Image) SetStartSegment(cs, ip uint16) {
+	img.start = &startAddr{cs: cs, ip: ip}
+}
+
+// ClearStart removes the start address of the Image.
+func (img *Image) ClearStart() {
+	img.start = nil
+}
+
+// EIP returns the start address set by a record of type 5 or by
+// SetStartLinear, with ok false if the Image has no such address.
+func (img *Image) EIP() (eip uint32, ok bool) {
+	if img.start == nil || !img.start.linear {
+		return 0, false
+	}
+	return img.start.eip, true
+}
+
+// CSIP returns the start address set by a record of type 3 or by
+// SetStartSegment, with ok false if the Image has no such address.
+func (img *Image) CSIP() (cs uint16, ip uint16, ok bool) {
+	if img.start == nil || img.start.linear {
+		return 0, 0, false
+	}
+	return img.start.cs, img.start.ip, true
+}
+
+// ReadAt reads len(b) bytes starting at address addr. If the range
+// runs past the end of the data it returns io.EOF, and if it includes
+// an address with no data it returns an error giving that address; in
+// either case it returns the number of bytes read before that point.
+func (img *Image) ReadAt(b []byte, addr int64) (int, error) {
+	if addr < 0 || addr >= 1<<32 {
+		return 0, fmt.Errorf("address %X outside of address space", addr)
+	}
+	n := 0
+	at := uint64(addr)
+	for i := img.segs.find(at); n < len(b); i++ {
+		if i == len(img.segs) {
+			return n, io.EOF
+		}
+		seg := img.segs[i]
+		start := uint64(seg.Address)
+		if start > at {
+			return n, fmt.Errorf("no data at %08X", at)
+		}
+		m := copy(b[n:], seg.Bytes[at-start:])
+		n += m
+		at += uint64(m)
+	}
+	return n, nil
+}
+
+// WriteAt writes b to the Image starting at address addr. It is an
+// error for the data to extend outside of the 32-bit address space.
+func (img *Image) WriteAt(b []byte, addr int64) (int, error) {
+	if addr < 0 || addr+int64(len(b)) > 1<<32 {
+		return 0, fmt.Errorf("data at %X-%X outside of address space",
+			addr, addr+int64(len(b))-1)
+	}
+	img.segs.add(Record{uint32(addr), b})
+	return len(b), nil
+}
+
+// Size returns the number of bytes of data that the Image holds, not
+// counting any gaps between its segments.
+func (img *Image) Size() int {
+	n := 0
+	for _, seg := range img.segs {
+		n += len(seg.Bytes)
+	}
+	return n
+}
+
+// Segments returns the contiguous segments of the Image, in address
+// order. The segments share storage with the Image, so they must not
+// be modified, and they are only valid until the Image is next written.
+func (img *Image) Segments() []Record {
+	return img.segs
+}
+
+// Bytes returns the data in the Image as a flat slice, from the lowest
+// address of any segment to the highest, with the gaps between segments
+// filled with fill. It returns nil for an empty Image.
+func (img *Image) Bytes(fill byte) []byte {
+	if len(img.segs) == 0 {
+		return nil
+	}
+	first := img.segs[0]
+	last := img.segs[len(img.segs)-1]
+	end := uint64(last.Address) + uint64(len(last.Bytes))
+	b := make([]byte, end-uint64(first.Address))
+	at := uint64(first.Address)
+	for _, seg := range img.segs {
+		start := uint64(seg.Address) - uint64(first.Address)
+		for i := at - uint64(first.Address); i < start; i++ {
+			b[i] = fill
+		}
+		copy(b[start:], seg.Bytes)
+		at = uint64(seg.Address) + uint64(len(seg.Bytes))
+	}
+	return b
+}
+
+// Pages returns the data in the Image split into pages of pageSize
+// bytes, each starting at an address that is a multiple of pageSize, as
+// for programming flash memory. Only pages that hold data are returned,
+// in address order, with the addresses in them that hold no data filled
+// with fill. It is an error for pageSize not to be greater than zero.
+func (img *Image) Pages(pageSize int, fill byte) ([]Record, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size %d", pageSize)
+	}
+	size := uint64(pageSize)
+	var pages []Record
+	for _, seg := range img.segs {
+		addr := uint64(seg.Address)
+		b := seg.Bytes
+		for len(b) > 0 {
+			start := addr - addr%size
+			if n := len(pages); n == 0 || uint64(pages[n-1].Address) != start {
+				page := bytes.Repeat([]byte{fill}, pageSize)
+				pages = append(pages, Record{uint32(start), page})
+			}
+			page := pages[len(pages)-1].Bytes
+			n := copy(page[addr-start:], b)
+			addr += uint64(n)
+			b = b[n:]
+		}
+	}
+	return pages, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, returning
+// the Image, with any start address, as the text of an Intel HEX file
+// written by a Writer with the default options.
+func (img *Image) MarshalText() ([]byte, error) {
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	if err := w.WriteImage(img); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface,
+// replacing the contents of the Image with the data and start address in
+// the text of an Intel HEX file. The Image is unchanged if there is an
+// error.
+func (img *Image) UnmarshalText(b []byte) error {
+	var loaded Image
+	if err := loaded.Load(ParseBytes(b)); err != nil {
+		return err
+	}
+	*img = loaded
+	return nil
+}
@@ -0,0 +1,154 @@
+package ihex
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestImage(t *testing.T) {
+	records := `:0401000001020304F1
+:020104000506EE
+:020108000708E6
+:01010100AA53
+:00000001FF
+`
+	img, err := ReadImage(strings.NewReader(records))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, img.Segments(), []Record{
+		{0x100, []byte{1, 0xaa, 3, 4, 5, 6}},
+		{0x108, []byte{7, 8}},
+	})
+	if img.Size() != 8 {
+		t.Errorf("expected size 8, got %d", img.Size())
+	}
+	want := []byte{1, 0xaa, 3, 4, 5, 6, 0xff, 0xff, 7, 8}
+	if got := img.Bytes(0xff); !bytes.Equal(got, want) {
+		t.Errorf("expected bytes % X, got % X", want, got)
+	}
+
+	b := make([]byte, 4)
+	n, err := img.ReadAt(b, 0x104)
+	if n != 2 || err == nil || err.Error() != "no data at 00000106" {
+		t.Errorf("expected 2 bytes and gap error, got %d, %v", n, err)
+	}
+	n, err = img.ReadAt(b[:2], 0x109)
+	if n != 1 || err != io.EOF || b[0] != 8 {
+		t.Errorf("expected 1 byte and EOF, got %d, %v", n, err)
+	}
+
+	img.WriteAt([]byte{9}, 0x106)
+	img.WriteAt([]byte{0xb}, 0x107)
+	checkRecords(t, img.Segments(), []Record{
+		{0x100, []byte{1, 0xaa, 3, 4, 5, 6, 9, 0xb, 7, 8}},
+	})
+	n, err = img.ReadAt(b, 0x105)
+	if n != 4 || err != nil || !bytes.Equal(b, []byte{6, 9, 0xb, 7}) {
+		t.Errorf("expected 06 09 0B 07, got % X (%d, %v)", b, n, err)
+	}
+	if _, err := img.WriteAt([]byte{1, 2}, 0xffffffff); err == nil {
+		t.Error("expected error for data outside of address space")
+	}
+}
+
+func TestMustReadImage(t *testing.T) {
+	img := MustReadImage([]byte(":0401000001020304F1\n:00000001FF\n"))
+	checkRecords(t, img.Segments(), []Record{{0x100, []byte{1, 2, 3, 4}}})
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("missed panic")
+		}
+	}()
+	MustReadImage([]byte(":00000001FE"))
+}
+
+func TestImageText(t *testing.T) {
+	text := ":0401000001020304F1\n:020000040001F9\n:0100000041BE\n:00000001FF\n"
+	var config struct {
+		Firmware *Image `json:"firmware"`
+	}
+	doc := `{"firmware":` + strconv.Quote(text) + `}`
+	if err := json.Unmarshal([]byte(doc), &config); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, config.Firmware.Segments(), []Record{
+		{0x100, []byte{1, 2, 3, 4}},
+		{0x10000, []byte{0x41}},
+	})
+	b, err := json.Marshal(config)
+	if err != nil || string(b) != doc {
+		t.Errorf("expected %s, got %s %v", doc, b, err)
+	}
+
+	img := MustReadImage([]byte(text))
+	if err := img.UnmarshalText([]byte(":00000001FE\n")); err == nil {
+		t.Error("missed invalid text")
+	}
+	if img.Size() != 5 {
+		t.Error("Image changed by failed UnmarshalText")
+	}
+}
+
+func TestImageStart(t *testing.T) {
+	img := MustReadImage([]byte(":0100000041BE\n:0400000500000100F6\n:00000001FF\n"))
+	if eip, ok := img.EIP(); !ok || eip != 0x100 {
+		t.Errorf("expected start 100, got %X %v", eip, ok)
+	}
+	img.SetStartSegment(0, 0x1234)
+	if _, ok := img.EIP(); ok {
+		t.Error("linear start address not replaced")
+	}
+	if cs, ip, ok := img.CSIP(); !ok || cs != 0 || ip != 0x1234 {
+		t.Errorf("expected start 0:1234, got %X:%X %v", cs, ip, ok)
+	}
+	text, _ := img.MarshalText()
+	if want := ":0100000041BE\n:0400000300001234B3\n:00000001FF\n"; string(text) != want {
+		t.Errorf("expected\n%s, got\n%s", want, text)
+	}
+
+	other := &Image{}
+	other.SetStartLinear(0x200)
+	if err := img.Merge(other, ConflictError); err == nil ||
+		err.Error() != "conflicting start address" {
+		t.Error("missed conflicting start address", err)
+	}
+	if err := img.Merge(other, LastWins); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if eip, ok := img.EIP(); !ok || eip != 0x200 {
+		t.Errorf("expected merged start 200, got %X %v", eip, ok)
+	}
+
+	img.ClearStart()
+	text, _ = img.MarshalText()
+	if want := ":0100000041BE\n:00000001FF\n"; string(text) != want {
+		t.Errorf("expected\n%s, got\n%s", want, text)
+	}
+}
+
+func TestPages(t *testing.T) {
+	img := &Image{}
+	img.WriteAt([]byte{1, 2, 3}, 0x0e)
+	img.WriteAt([]byte{4}, 0x13)
+	img.WriteAt([]byte{5}, 0x31)
+	pages, err := img.Pages(8, 0xff)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, pages, []Record{
+		{0x08, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 2}},
+		{0x10, []byte{3, 0xff, 0xff, 4, 0xff, 0xff, 0xff, 0xff}},
+		{0x30, []byte{0xff, 5, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	})
+	for _, size := range []int{0, -1} {
+		if _, err := img.Pages(size, 0xff); err == nil {
+			t.Errorf("expected error for page size %d", size)
+		}
+	}
+}
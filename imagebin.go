@@ -0,0 +1,126 @@
+package ihex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// The binary form of an Image starts with imageMagic and a version
+// byte, then a byte saying what kind of start address follows:
+// startNone, startLinear (followed by the 4-byte eip), or startSegment
+// (followed by the 2-byte cs and ip). Then comes the 4-byte number of
+// segments, and for each segment its 4-byte address, 4-byte length and
+// data. All numbers are big-endian.
+const (
+	imageMagic   = "IHXB"
+	imageVersion = 1
+
+	startNone    = 0
+	startLinear  = 1
+	startSegment = 2
+)
+
+var errImageFormat = errors.New("invalid binary Image")
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface,
+// returning the segments and start address of the Image in a compact
+// binary form, which is much faster to read back than Intel HEX text.
+func (img *Image) MarshalBinary() ([]byte, error) {
+	b := append([]byte(imageMagic), imageVersion)
+	switch s := img.start; {
+	case s == nil:
+		b = append(b, startNone)
+	case s.linear:
+		b = append(b, startLinear)
+		b = binary.BigEndian.AppendUint32(b, s.eip)
+	default:
+		b = append(b, startSegment)
+		b = binary.BigEndian.AppendUint16(b, s.cs)
+		b = binary.BigEndian.AppendUint16(b, s.ip)
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(img.segs)))
+	for _, seg := range img.segs {
+		b = binary.BigEndian.AppendUint32(b, seg.Address)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(seg.Bytes)))
+		b = append(b, seg.Bytes...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// replacing the contents of the Image with those in b, which must be in
+// the form returned by MarshalBinary. The Image is unchanged if there is
+// an error.
+func (img *Image) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, []byte(imageMagic)) {
+		return errImageFormat
+	}
+	d := imageDecoder{b: b[len(imageMagic):], ok: true}
+	if d.uint8() != imageVersion {
+		return errors.New("unsupported binary Image version")
+	}
+	var loaded Image
+	switch d.uint8() {
+	case startNone:
+	case startLinear:
+		loaded.start = &startAddr{linear: true, eip: d.uint32()}
+	case startSegment:
+		loaded.start = &startAddr{cs: d.uint16(), ip: d.uint16()}
+	default:
+		return errImageFormat
+	}
+	for n := d.uint32(); n > 0 && d.ok; n-- {
+		addr, size := d.uint32(), d.uint32()
+		data := d.bytes(size)
+		if !d.ok || uint64(addr)+uint64(size) > 1<<32 {
+			return errImageFormat
+		}
+		if size > 0 {
+			loaded.segs.add(Record{addr, bytes.Clone(data)})
+		}
+	}
+	if !d.ok || len(d.b) > 0 {
+		return errImageFormat
+	}
+	*img = loaded
+	return nil
+}
+
+// An imageDecoder reads the fields of the binary form of an Image,
+// setting ok to false if it runs out of input.
+type imageDecoder struct {
+	b  []byte
+	ok bool
+}
+
+func (d *imageDecoder) bytes(n uint32) []byte {
+	if uint64(len(d.b)) < uint64(n) {
+		d.ok, d.b = false, nil
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *imageDecoder) uint8() byte {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *imageDecoder) uint16() uint16 {
+	if b := d.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *imageDecoder) uint32() uint32 {
+	if b := d.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
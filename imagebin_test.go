@@ -0,0 +1,57 @@
+package ihex
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestImageBinary(t *testing.T) {
+	img := MustReadImage([]byte(":0401000001020304F1\n:020000040001F9\n" +
+		":0100000041BE\n:0400000500000100F6\n:00000001FF\n"))
+	b, err := img.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := []byte("IHXB\x01\x01\x00\x00\x01\x00\x00\x00\x00\x02" +
+		"\x00\x00\x01\x00\x00\x00\x00\x04\x01\x02\x03\x04" +
+		"\x00\x01\x00\x00\x00\x00\x00\x01\x41")
+	if !bytes.Equal(b, want) {
+		t.Errorf("expected % X, got % X", want, b)
+	}
+
+	var got Image
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, got.Segments(), img.Segments())
+	if eip, ok := got.EIP(); !ok || eip != 0x100 {
+		t.Errorf("expected start 100, got %X %v", eip, ok)
+	}
+
+	got.SetStartSegment(1, 2)
+	b, _ = got.MarshalBinary()
+	var again Image
+	if err := again.UnmarshalBinary(b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if cs, ip, ok := again.CSIP(); !ok || cs != 1 || ip != 2 {
+		t.Errorf("expected start 1:2, got %X:%X %v", cs, ip, ok)
+	}
+
+	for _, bad := range [][]byte{
+		nil,
+		[]byte("IHXA\x01\x00\x00\x00\x00\x00"),
+		[]byte("IHXB\x02\x00\x00\x00\x00\x00"),
+		[]byte("IHXB\x01\x03\x00\x00\x00\x00"),
+		want[:len(want)-1],
+		append(bytes.Clone(want), 0),
+		[]byte("IHXB\x01\x00\x00\x00\x00\x01\xff\xff\xff\xff\x00\x00\x00\x02\x01\x02"),
+	} {
+		if err := again.UnmarshalBinary(bad); err == nil {
+			t.Errorf("missed invalid input % X", bad)
+		}
+	}
+	if again.Size() != 5 {
+		t.Error("Image changed by failed UnmarshalBinary")
+	}
+}
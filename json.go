@@ -0,0 +1,256 @@
+package ihex
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A DataFormat selects how ExportJSON encodes data bytes.
+type DataFormat int
+
+const (
+	HexData    DataFormat = iota // a string of hex digits
+	Base64Data                   // a string in standard base64
+)
+
+// A JSONRecord is the JSON form of a record. For a data record, Address
+// is the absolute address of its first byte; for other records, it is
+// the load offset field. The data bytes are held by either Hex or
+// Base64, depending on the DataFormat used.
+type JSONRecord struct {
+	Type    int    `json:"type"`
+	Address uint32 `json:"address"`
+	Line    int    `json:"line,omitempty"`
+	Hex     string `json:"hex,omitempty"`
+	Base64  string `json:"base64,omitempty"`
+}
+
+// ExportJSON writes each record returned by p to w as a JSONRecord on
+// a line of its own (newline-delimited JSON), for processing with tools
+// such as jq. To write records of every type, p must have the
+// AllRecords option; otherwise only data records are written. A data
+// record that wraps around a segment is written as two records. It
+// returns the first error from p or w.
+func ExportJSON(w io.Writer, p *Parser, format DataFormat) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for p.Parse() {
+		var rec JSONRecord
+		var b []byte
+		switch {
+		case p.HasData():
+			data := p.Data()
+			rec.Address, b = data.Address, data.Bytes
+		case p.isRecord && p.allRecords:
+			raw := p.Raw()
+			rec.Type, rec.Address, b = int(raw.Type), uint32(raw.Offset), raw.Data
+		default:
+			// a line returned only for the Tee option
+			continue
+		}
+		rec.Line = p.LineNumber()
+		if format == Base64Data {
+			rec.Base64 = base64.StdEncoding.EncodeToString(b)
+		} else {
+			rec.Hex = hex.EncodeToString(b)
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// A JSONReader reads records in the form written by ExportJSON, with an
+// interface similar to that of a Parser with the AllRecords option, so
+// that what ExportJSON writes can be read back without loss. Records of
+// every type are returned. The address of a data record is absolute,
+// and its load offset is recovered from the last record of type 2 or 4;
+// the address of any other record is its load offset. A record of types
+// 1 to 5 must have the length that the format requires. Objects with
+// unknown fields are accepted.
+type JSONReader struct {
+	dec  *json.Decoder
+	data Record
+	raw  RawRecord
+	base uint32 // from the last record of type 2 or 4
+	line int
+	n    int
+	err  error
+}
+
+// NewJSONReader returns a new JSONReader to read from r.
+func NewJSONReader(r io.Reader) *JSONReader {
+	return &JSONReader{dec: json.NewDecoder(r)}
+}
+
+// Parse reads the next record, which can then be accessed by the Raw
+// method, and for a data record by the Data method. It returns false
+// when there are no more records, or if an error occurred; an error,
+// if any, can be accessed by the Err method.
+func (j *JSONReader) Parse() bool {
+	if j.err != nil {
+		return false
+	}
+	var rec JSONRecord
+	if err := j.dec.Decode(&rec); err != nil {
+		if err != io.EOF {
+			j.err = fmt.Errorf("record %d: %v", j.n+1, err)
+		}
+		return false
+	}
+	j.n++
+	b, err := rec.decode()
+	if err != nil {
+		j.err = fmt.Errorf("record %d: %v", j.n, err)
+		return false
+	}
+	j.line = rec.Line
+	j.raw = RawRecord{Type: byte(rec.Type), Offset: uint16(rec.Address),
+		Data: b}
+	j.data = Record{}
+	switch rec.Type {
+	case 0:
+		j.raw.Offset = uint16(rec.Address - j.base)
+		j.raw.Address = rec.Address
+		j.data = Record{rec.Address, b}
+	case 2:
+		j.base = uint32(binary.BigEndian.Uint16(b)) << 4
+	case 4:
+		j.base = uint32(binary.BigEndian.Uint16(b)) << 16
+	}
+	j.raw.Checksum = rawChecksum(j.raw)
+	return true
+}
+
+func (rec JSONRecord) decode() ([]byte, error) {
+	if rec.Type < 0 || rec.Type > 0xff {
+		return nil, fmt.Errorf("invalid record type %d", rec.Type)
+	}
+	if rec.Type != 0 && rec.Address > 0xffff {
+		return nil, fmt.Errorf("invalid load offset %d", rec.Address)
+	}
+	if rec.Hex != "" && rec.Base64 != "" {
+		return nil, errors.New("both hex and base64 data")
+	}
+	var b []byte
+	var err error
+	if rec.Base64 != "" {
+		b, err = base64.StdEncoding.DecodeString(rec.Base64)
+	} else {
+		b, err = hex.DecodeString(rec.Hex)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0xff || rec.Type > 0 && rec.Type < len(reclens) &&
+		len(b) != int(reclens[rec.Type]) {
+		return nil, errors.New("invalid record length")
+	}
+	return b, nil
+}
+
+// rawChecksum returns the checksum that the record r would have in a
+// HEX file.
+func rawChecksum(r RawRecord) byte {
+	sum := byte(len(r.Data)) + byte(r.Offset>>8) + byte(r.Offset) + r.Type
+	for _, c := range r.Data {
+		sum += c
+	}
+	return -sum
+}
+
+// Data returns the last record read by the Parse method, or an empty
+// Record if it was not a data record.
+func (j *JSONReader) Data() Record {
+	return j.data
+}
+
+// Raw returns the fields of the last record read by the Parse method.
+func (j *JSONReader) Raw() RawRecord {
+	return j.raw
+}
+
+// HasData reports whether the last call to Parse read a data record.
+func (j *JSONReader) HasData() bool {
+	return j.raw.Type == 0
+}
+
+// LineNumber returns the line number stored with the last record read
+// by the Parse method, or 0 if it had none.
+func (j *JSONReader) LineNumber() int {
+	return j.line
+}
+
+// Err returns the first error that was encountered by the JSONReader.
+func (j *JSONReader) Err() error {
+	return j.err
+}
+
+// ReadJSONImage returns an Image holding the data records read from r
+// by a JSONReader, with its start address set from any record of type
+// 3 or 5, preferring type 5 if there are both.
+func ReadJSONImage(r io.Reader) (*Image, error) {
+	img := &Image{}
+	j := NewJSONReader(r)
+	var start *startAddr
+	for j.Parse() {
+		raw := j.Raw()
+		switch raw.Type {
+		case 0:
+			img.segs.add(j.Data())
+		case 3:
+			if start == nil || !start.linear {
+				start = &startAddr{
+					cs: binary.BigEndian.Uint16(raw.Data),
+					ip: binary.BigEndian.Uint16(raw.Data[2:]),
+				}
+			}
+		case 5:
+			start = &startAddr{linear: true,
+				eip: binary.BigEndian.Uint32(raw.Data)}
+		}
+	}
+	if err := j.Err(); err != nil {
+		return nil, err
+	}
+	img.start = start
+	return img, nil
+}
+
+// A MapSegment describes a segment of an Image in the document returned
+// by MarshalMap.
+type MapSegment struct {
+	Start  uint32 `json:"start"`
+	Length int    `json:"length"`
+	SHA256 string `json:"sha256"` // hex digest of the segment's data
+}
+
+// MarshalMap returns a JSON document describing the layout of the
+// Image, for release manifests and flashing scripts. It is an object
+// whose "segments" field holds a MapSegment for each segment of the
+// Image, in address order.
+func (img *Image) MarshalMap() ([]byte, error) {
+	doc := struct {
+		Segments []MapSegment `json:"segments"`
+	}{Segments: []MapSegment{}}
+	for _, seg := range img.segs {
+		sum := sha256.Sum256(seg.Bytes)
+		doc.Segments = append(doc.Segments, MapSegment{
+			Start:  seg.Address,
+			Length: len(seg.Bytes),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,197 @@
+package ihex
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExportJSON(t *testing.T) {
+	records := `
+:020000021200EA
+:02FFFF00000000
+:0B0010006164647265737320676170A7
+:00000001FF
+`
+	var buf bytes.Buffer
+	err := ExportJSON(&buf, ParseString(records, AllRecords()), HexData)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	expected := `{"type":2,"address":0,"line":2,"hex":"1200"}
+{"type":0,"address":139263,"line":3,"hex":"00"}
+{"type":0,"address":73728,"line":3,"hex":"00"}
+{"type":0,"address":73744,"line":4,"hex":"6164647265737320676170"}
+{"type":1,"address":0,"line":5}
+`
+	if buf.String() != expected {
+		t.Errorf("incorrect output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	err = ExportJSON(&buf, ParseString(records, AllRecords()), Base64Data)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"base64":"YWRkcmVzcyBnYXA="}`+"\n")) {
+		t.Errorf("incorrect output:\n%s", buf.String())
+	}
+
+	if ExportJSON(&buf, ParseString(":00000001FE"), HexData) == nil {
+		t.Error("missed parse error")
+	}
+
+	buf.Reset()
+	p := ParseString("junk\n:0400000500000100F6\n:00000001FF\n", Tee(),
+		NonRecordLines(SkipLines), AllRecords())
+	if err := ExportJSON(&buf, p, HexData); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	expected = `{"type":5,"address":0,"line":2,"hex":"00000100"}
+{"type":1,"address":0,"line":3}
+`
+	if buf.String() != expected {
+		t.Errorf("incorrect output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	p = ParseString(records, Tee())
+	if err := ExportJSON(&buf, p, HexData); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if strings.Contains(buf.String(), `"type":2`) {
+		t.Errorf("expected only data records without AllRecords, got\n%s", buf.String())
+	}
+	if p.allRecords {
+		t.Error("ExportJSON changed the options of its Parser")
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	input := `{"type":0,"address":16,"line":3,"hex":"6164"}
+{"type":0,"address":18,"base64":"ZHI=","source":"boot"}
+{"type":4,"address":0,"hex":"0001"}
+
+{"address":20}
+`
+	j := NewJSONReader(strings.NewReader(input))
+	var recs []Record
+	var types []byte
+	for j.Parse() {
+		if j.HasData() {
+			recs = append(recs, j.Data())
+		}
+		types = append(types, j.Raw().Type)
+	}
+	if j.Err() != nil {
+		t.Fatal("unexpected error", j.Err())
+	}
+	checkRecords(t, recs, []Record{
+		{16, []byte("ad")},
+		{18, []byte("dr")},
+		{20, nil},
+	})
+	if !bytes.Equal(types, []byte{0, 0, 4, 0}) {
+		t.Errorf("expected types [0 0 4 0], got %v", types)
+	}
+
+	var cases = [][]string{
+		{`{"type":1,"address":0} {"type":256,"address":0}`,
+			"record 2: invalid record type 256"},
+		{`{"type":4,"hex":"00"}`, "record 1: invalid record length"},
+		{`{"type":2,"address":65536,"hex":"0000"}`,
+			"record 1: invalid load offset 65536"},
+		{`{"hex":"00"} {"hex":"0"}`, "record 2: encoding/hex: "},
+		{`{"hex":"00","base64":"AA=="}`, "record 1: both hex and base64 data"},
+		{`{"address":-1}`, "record 1: json: "},
+	}
+	for _, data := range cases {
+		j := NewJSONReader(strings.NewReader(data[0]))
+		for j.Parse() {
+		}
+		if j.Err() == nil || !strings.HasPrefix(j.Err().Error(), data[1]) {
+			t.Errorf("expected %q, got %v", data[1], j.Err())
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	records := `
+:0B0010006164647265737320676170A7
+:02FFFF00000000
+:00000001FF
+`
+	for _, format := range []DataFormat{HexData, Base64Data} {
+		var buf bytes.Buffer
+		if err := ExportJSON(&buf, ParseString(records), format); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		j := NewJSONReader(&buf)
+		var recs []Record
+		for j.Parse() {
+			recs = append(recs, j.Data())
+		}
+		if j.Err() != nil {
+			t.Fatal("unexpected error", j.Err())
+		}
+		checkRecords(t, recs, MustParseRecords([]byte(records)))
+	}
+
+	// every record survives with the AllRecords option
+	records = ":020000021200EA\n:0400000300120034B3\n:0400000500001234B1\n" +
+		":03001000616464C4\n:01000006AA4F\n:00000001FF\n"
+	var want []string
+	p := ParseString(records, AllRecords())
+	for p.Parse() {
+		want = append(want, fmt.Sprintf("%d %+v", p.LineNumber(), p.Raw()))
+	}
+	var buf bytes.Buffer
+	if err := ExportJSON(&buf, ParseString(records, AllRecords()), HexData); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var got []string
+	j := NewJSONReader(bytes.NewReader(buf.Bytes()))
+	for j.Parse() {
+		got = append(got, fmt.Sprintf("%d %+v", j.LineNumber(), j.Raw()))
+	}
+	if j.Err() != nil {
+		t.Fatal("unexpected error", j.Err())
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected\n%v, got\n%v", want, got)
+	}
+
+	img, err := ReadJSONImage(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	ref := MustReadImage([]byte(records), AllRecords())
+	if fmt.Sprint(img.Segments()) != fmt.Sprint(ref.Segments()) {
+		t.Errorf("expected segments %v, got %v", ref.Segments(), img.Segments())
+	}
+	if eip, ok := img.EIP(); !ok || eip != 0x1234 {
+		t.Errorf("expected start 1234, got %X %v", eip, ok)
+	}
+}
+
+func TestMarshalMap(t *testing.T) {
+	img := MustReadImage([]byte(":0401000001020304F1\n:020000040001F9\n" +
+		":0100000041BE\n:00000001FF\n"))
+	b, err := img.MarshalMap()
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `{"segments":[` +
+		`{"start":256,"length":4,"sha256":` +
+		`"9f64a747e1b97f131fabb6b447296c9b6f0201e79fb3c5356e6c77e89b6a806a"},` +
+		`{"start":65536,"length":1,"sha256":` +
+		`"559aead08264d5795d3909718cdd05abd49572e84fe55590eef31a88a08fdffd"}]}`
+	if string(b) != want {
+		t.Errorf("expected %s, got %s", want, b)
+	}
+
+	if b, _ := new(Image).MarshalMap(); string(b) != `{"segments":[]}` {
+		t.Errorf("incorrect map of empty Image %s", b)
+	}
+}
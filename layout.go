@@ -0,0 +1,48 @@
+package ihex
+
+// OptimizeLayout makes the Writer hold the data it is given until Close,
+// and then write it in order of address, with data from separate calls
+// to WriteData merged into as few records as it fits in, and with an
+// extended address record only where the data moves out of the window
+// selected by the last one. This keeps the output of a sparse image,
+// or of data written out of order, from needing more lines than its
+// data does. With SegmentAddressing, each extended segment address
+// record selects the window that starts as close below the next data
+// as it can, so that one record can cover data on both sides of a 64K
+// boundary. Any start address records are written after the data. If
+// data is written more than once to the same address, the last write
+// wins.
+//
+// If stable is true, windows start at 64K boundaries, and each data
+// record is split at multiples of the record length, so that where each
+// line goes depends only on the addresses of its data. This costs a few
+// lines, but a change to some of the data then changes only the lines
+// that hold it, which keeps the diffs between builds small.
+func OptimizeLayout(stable bool) WriterOption {
+	return func(w *Writer) {
+		w.pending = &Image{}
+		w.stable = stable
+	}
+}
+
+// baseFor returns the start of the window to select for data at addr.
+func (w *Writer) baseFor(addr uint32) uint32 {
+	if w.segment && w.pending != nil && !w.stable {
+		return addr &^ 0xf
+	}
+	return addr &^ 0xffff
+}
+
+// flushLayout writes the data and records held for OptimizeLayout.
+func (w *Writer) flushLayout() {
+	if w.pending == nil {
+		return
+	}
+	for _, seg := range w.pending.segs {
+		w.writeData(seg.Address, seg.Bytes)
+	}
+	for _, r := range w.held {
+		w.record(r.Type, 0, r.Data)
+	}
+	w.held = nil
+}
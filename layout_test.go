@@ -0,0 +1,68 @@
+package ihex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptimizeLayout(t *testing.T) {
+	tests := []struct {
+		stable bool
+		want   string
+	}{
+		{false, `:020000021000EC
+:020000000102FB
+:020000022FFFCE
+:100008000102030405060708090A0B0C0D0E0F1060
+:0400000312345678E5
+:00000001FF
+`},
+		{true, `:020000021000EC
+:020000000102FB
+:020000022000DC
+:08FFF8000102030405060708DD
+:020000023000CC
+:08000000090A0B0C0D0E0F1094
+:0400000312345678E5
+:00000001FF
+`},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		w := NewWriter(&b, SegmentAddressing(), OptimizeLayout(tt.stable))
+		w.WriteData(0x2fff8, []byte{
+			1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+		w.WriteCSIP(0x1234, 0x5678)
+		w.WriteData(0x10000, []byte{1})
+		w.WriteData(0x10001, []byte{2})
+		if err := w.Close(); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("stable %v: expected\n%s\ngot\n%s", tt.stable, tt.want, b.String())
+		}
+		img, err := ReadImage(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		checkRecords(t, img.Segments(), []Record{
+			{0x10000, []byte{1, 2}},
+			{0x2fff8, []byte{
+				1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}},
+		})
+	}
+
+	// without SegmentAddressing, the data is still sorted and merged
+	var b strings.Builder
+	w := NewWriter(&b, OptimizeLayout(false))
+	w.WriteData(0x10001, []byte{2})
+	w.WriteData(0x10000, []byte{1})
+	w.Close()
+	want := `:020000040001F9
+:020000000102FB
+:00000001FF
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,120 @@
+package ihex
+
+import "sort"
+
+// A LineSpan associates a range of addresses with the line of a file
+// whose data record defines them.
+type LineSpan struct {
+	File    string
+	Line    int
+	Address uint32
+	Len     int
+}
+
+// A LineMap maps between addresses and the lines of HEX files that
+// define them. The zero value is an empty LineMap ready to use.
+type LineMap struct {
+	spans []LineSpan
+	lines map[fileLine][]int
+	index []indexEntry
+	stale bool
+}
+
+type fileLine struct {
+	file string
+	line int
+}
+
+// An indexEntry maps the addresses in [start, end) to a span. The
+// entries of a LineMap's index do not overlap, and are sorted by
+// address.
+type indexEntry struct {
+	start, end uint64
+	span       int
+}
+
+// ReadLineMap returns a LineMap built from the data records read by p,
+// which reads the HEX file named file.
+func ReadLineMap(file string, p *Parser) (*LineMap, error) {
+	m := &LineMap{}
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		m.Add(file, p.LineNumber(), p.Data())
+	}
+	return m, p.Err()
+}
+
+// Add records that r was read from the given line of file. Where records
+// overlap, addresses map to the record that was added last.
+func (m *LineMap) Add(file string, line int, r Record) {
+	if len(r.Bytes) == 0 {
+		return
+	}
+	if m.lines == nil {
+		m.lines = make(map[fileLine][]int)
+	}
+	key := fileLine{file, line}
+	m.lines[key] = append(m.lines[key], len(m.spans))
+	m.spans = append(m.spans, LineSpan{file, line, r.Address, len(r.Bytes)})
+	m.stale = true
+}
+
+// Lookup returns the span of the line that defines addr, with ok false
+// if no line does.
+func (m *LineMap) Lookup(addr uint32) (span LineSpan, ok bool) {
+	if m.stale {
+		m.buildIndex()
+	}
+	i := m.search(uint64(addr))
+	if i == len(m.index) || m.index[i].start > uint64(addr) {
+		return LineSpan{}, false
+	}
+	return m.spans[m.index[i].span], true
+}
+
+// Line returns the address ranges defined by the given line of file. A
+// record split at a segment boundary has two ranges.
+func (m *LineMap) Line(file string, line int) []LineSpan {
+	var spans []LineSpan
+	for _, i := range m.lines[fileLine{file, line}] {
+		spans = append(spans, m.spans[i])
+	}
+	return spans
+}
+
+// search returns the index of the first entry that ends after addr.
+func (m *LineMap) search(addr uint64) int {
+	return sort.Search(len(m.index), func(i int) bool {
+		return m.index[i].end > addr
+	})
+}
+
+// buildIndex lays the spans over each other in the order they were
+// added.
+func (m *LineMap) buildIndex() {
+	m.index = m.index[:0]
+	for n, s := range m.spans {
+		e := indexEntry{uint64(s.Address), uint64(s.Address) + uint64(s.Len), n}
+		i := m.search(e.start)
+		j := i
+		for j < len(m.index) && m.index[j].start < e.end {
+			j++
+		}
+		pieces := []indexEntry{e}
+		if i < j {
+			// keep the parts of the overlapped entries outside of e
+			if first := m.index[i]; first.start < e.start {
+				first.end = e.start
+				pieces = append([]indexEntry{first}, pieces...)
+			}
+			if last := m.index[j-1]; last.end > e.end {
+				last.start = e.end
+				pieces = append(pieces, last)
+			}
+		}
+		m.index = append(m.index[:i], append(pieces, m.index[j:]...)...)
+	}
+	m.stale = false
+}
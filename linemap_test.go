@@ -0,0 +1,55 @@
+package ihex
+
+import "testing"
+
+func TestLineMap(t *testing.T) {
+	records := `
+:020000021200EA
+:02FFFF00000000
+:0B0010006164647265737320676170A7
+:00000001FF
+`
+	m, err := ReadLineMap("fw.hex", ParseString(records))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	m.Add("patch.hex", 7, Record{0x12014, []byte{0, 0}})
+
+	var cases = []struct {
+		addr uint32
+		file string
+		line int
+	}{
+		{0x21fff, "fw.hex", 3},
+		{0x12000, "fw.hex", 3},
+		{0x12010, "fw.hex", 4},
+		{0x12014, "patch.hex", 7},
+		{0x12015, "patch.hex", 7},
+		{0x12016, "fw.hex", 4},
+		{0x1201a, "fw.hex", 4},
+	}
+	for _, c := range cases {
+		span, ok := m.Lookup(c.addr)
+		if !ok || span.File != c.file || span.Line != c.line {
+			t.Errorf("%X: expected %s:%d, got %v", c.addr, c.file, c.line, span)
+		}
+	}
+	for _, addr := range []uint32{0x12001, 0x1201b, 0} {
+		if _, ok := m.Lookup(addr); ok {
+			t.Errorf("%X: unexpected line", addr)
+		}
+	}
+
+	spans := m.Line("fw.hex", 3)
+	if len(spans) != 2 || spans[0].Address != 0x21fff ||
+		spans[1].Address != 0x12000 || spans[1].Len != 1 {
+		t.Error("incorrect spans", spans)
+	}
+	spans = m.Line("fw.hex", 4)
+	if len(spans) != 1 || spans[0].Address != 0x12010 || spans[0].Len != 11 {
+		t.Error("incorrect spans", spans)
+	}
+	if len(m.Line("fw.hex", 1)) != 0 {
+		t.Error("unexpected spans for line 1")
+	}
+}
@@ -0,0 +1,333 @@
+package ihex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A MapSection is a section listed in a linker map file.
+type MapSection struct {
+	Name    string
+	Address uint32
+	Size    uint32
+	File    string // the object file, for an input section
+}
+
+// A MapSymbol is a symbol listed in a linker map file.
+type MapSymbol struct {
+	Name    string
+	Address uint32
+}
+
+// A LinkerMap holds the sections and symbols from a linker map file,
+// each sorted by address, and the memory regions from a GNU linker map
+// file, in the order listed.
+type LinkerMap struct {
+	Sections      []MapSection // output sections
+	InputSections []MapSection
+	Symbols       []MapSymbol
+	Memory        MemoryMap
+}
+
+// debugSections are prefixes of the names of sections that do not
+// occupy target memory, which ReadLinkerMap ignores.
+var debugSections = []string{
+	".debug", ".comment", ".stab", ".ARM.attributes", ".note.gnu",
+}
+
+// ReadLinkerMap reads a map file produced by the GNU linker (with -Map)
+// or by LLD (with --Map), returning its allocated sections and symbols.
+// Sections without size, sections holding debugging information, and
+// entries with addresses beyond 32 bits are ignored.
+func ReadLinkerMap(r io.Reader) (*LinkerMap, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	m := &LinkerMap{}
+	var err error
+	if len(lines) > 0 && strings.Contains(lines[0], "VMA") {
+		err = m.readLLD(lines)
+	} else {
+		err = m.readGNU(lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(m.Sections, func(i, j int) bool {
+		return m.Sections[i].Address < m.Sections[j].Address
+	})
+	sort.SliceStable(m.InputSections, func(i, j int) bool {
+		return m.InputSections[i].Address < m.InputSections[j].Address
+	})
+	sort.SliceStable(m.Symbols, func(i, j int) bool {
+		return m.Symbols[i].Address < m.Symbols[j].Address
+	})
+	return m, nil
+}
+
+// readGNU reads the memory map part of a GNU linker map file, in which
+// output sections start in the first column, input sections start in
+// the second, and symbols follow a column of spaces. A section name that
+// is too long is followed by its address and size on the next line.
+func (m *LinkerMap) readGNU(lines []string) error {
+	start := -1
+	memory := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Linker script and memory map") {
+			start = i + 1
+			break
+		}
+		if strings.HasPrefix(line, "Memory Configuration") {
+			memory = true
+			continue
+		}
+		if memory {
+			m.addMemory(strings.Fields(line))
+		}
+	}
+	if start < 0 {
+		return errors.New("linker map: no memory map found")
+	}
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent <= 1 && !strings.HasPrefix(fields[0], "0x") {
+			name := fields[0]
+			if len(fields) == 1 && i+1 < len(lines) {
+				// the address and size are on the next line
+				next := strings.Fields(lines[i+1])
+				if len(next) >= 2 && strings.HasPrefix(next[0], "0x") {
+					fields = append(fields, next...)
+					i++
+				}
+			}
+			if len(fields) < 3 || strings.HasPrefix(name, "*") {
+				continue
+			}
+			addr, ok1 := parseMapNumber(fields[1], 0)
+			size, ok2 := parseMapNumber(fields[2], 0)
+			if !ok1 || !ok2 {
+				continue
+			}
+			s := MapSection{Name: name, Address: addr, Size: size}
+			if indent == 0 {
+				m.addSection(s)
+			} else {
+				s.File = strings.Join(fields[3:], " ")
+				m.addInputSection(s)
+			}
+			continue
+		}
+		// a symbol has only an address and a name, while assignments
+		// and other script statements have more
+		if len(fields) == 2 && strings.HasPrefix(fields[0], "0x") &&
+			!strings.ContainsAny(fields[1], "=(") {
+			if addr, ok := parseMapNumber(fields[0], 0); ok {
+				m.addSymbol(MapSymbol{fields[1], addr})
+			}
+		}
+	}
+	return nil
+}
+
+// readLLD reads an LLD map file, which has columns of hexadecimal
+// numbers (VMA, LMA, Size, Align; older versions have Address, Size,
+// Align) followed by a name whose column identifies it as an output
+// section, an input section, or a symbol.
+func (m *LinkerMap) readLLD(lines []string) error {
+	header := lines[0]
+	numCols := 0
+	if outCol := strings.Index(header, "Out"); outCol >= 0 {
+		numCols = len(strings.Fields(header[:outCol]))
+	}
+	inCol := strings.Index(header, "In ")
+	symCol := strings.Index(header, "Symbol")
+	if numCols < 3 || inCol < 0 || symCol < 0 {
+		return errors.New("linker map: unrecognized LLD map header")
+	}
+	sizeCol := numCols - 2
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) <= numCols {
+			continue
+		}
+		addr, ok1 := parseMapNumber(fields[0], 16)
+		size, ok2 := parseMapNumber(fields[sizeCol], 16)
+		if !ok1 || !ok2 {
+			continue
+		}
+		name := strings.Join(fields[numCols:], " ")
+		col := strings.Index(line, fields[numCols])
+		switch {
+		case col < inCol:
+			m.addSection(MapSection{Name: name, Address: addr, Size: size})
+		case col < symCol:
+			file, sect := "", name
+			if i := strings.LastIndex(name, ":("); i >= 0 &&
+				strings.HasSuffix(name, ")") {
+				file, sect = name[:i], name[i+2:len(name)-1]
+			}
+			m.addInputSection(MapSection{sect, addr, size, file})
+		default:
+			m.addSymbol(MapSymbol{name, addr})
+		}
+	}
+	return nil
+}
+
+func parseMapNumber(s string, base int) (uint32, bool) {
+	n, err := strconv.ParseUint(s, base, 32)
+	return uint32(n), err == nil
+}
+
+func isDebugSection(name string) bool {
+	for _, prefix := range debugSections {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// addMemory adds the memory region described by the fields of a line
+// of the memory configuration of a GNU linker map file, if it is one.
+func (m *LinkerMap) addMemory(fields []string) {
+	if len(fields) < 3 || fields[0] == "*default*" {
+		return
+	}
+	origin, ok1 := parseMapNumber(fields[1], 0)
+	length, ok2 := parseMapNumber(fields[2], 0)
+	if ok1 && ok2 {
+		m.Memory = append(m.Memory, MemoryRegion{fields[0], origin, length})
+	}
+}
+
+func (m *LinkerMap) addSection(s MapSection) {
+	if s.Size > 0 && !isDebugSection(s.Name) {
+		m.Sections = append(m.Sections, s)
+	}
+}
+
+func (m *LinkerMap) addInputSection(s MapSection) {
+	if s.Size > 0 && !isDebugSection(s.Name) {
+		m.InputSections = append(m.InputSections, s)
+	}
+}
+
+func (m *LinkerMap) addSymbol(s MapSymbol) {
+	if _, ok := m.section(s.Address); ok {
+		m.Symbols = append(m.Symbols, s)
+	}
+}
+
+// section returns the output section that contains addr.
+func (m *LinkerMap) section(addr uint32) (MapSection, bool) {
+	for _, s := range m.Sections {
+		end := uint64(s.Address) + uint64(s.Size)
+		if addr >= s.Address && uint64(addr) < end {
+			return s, true
+		}
+	}
+	return MapSection{}, false
+}
+
+// Lookup returns the output section that contains addr, and the symbol
+// at the highest address not above addr in that section, if there is
+// one. It returns ok false if no section contains addr.
+func (m *LinkerMap) Lookup(addr uint32) (section MapSection,
+	symbol MapSymbol, ok bool) {
+	section, ok = m.section(addr)
+	if !ok {
+		return
+	}
+	i := sort.Search(len(m.Symbols), func(i int) bool {
+		return m.Symbols[i].Address > addr
+	})
+	if i > 0 && m.Symbols[i-1].Address >= section.Address {
+		symbol = m.Symbols[i-1]
+	}
+	return
+}
+
+// A MapRegion is a run of data from an Image with the names of the
+// output section and symbol that contain it, as returned by Image.Label.
+type MapRegion struct {
+	Record
+	Section string // empty for data outside every section
+	Symbol  string // empty for data before the first symbol of a section
+}
+
+// Label splits the data in the Image at the start and end of each
+// output section in m, and at the address of each symbol, returning
+// the pieces in address order, each named as by m.Lookup. The Bytes of
+// each MapRegion share memory with the Image.
+func (img *Image) Label(m *LinkerMap) []MapRegion {
+	var cuts []uint64
+	for _, s := range m.Sections {
+		cuts = append(cuts, uint64(s.Address),
+			uint64(s.Address)+uint64(s.Size))
+	}
+	for _, s := range m.Symbols {
+		cuts = append(cuts, uint64(s.Address))
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+	var regions []MapRegion
+	for _, seg := range img.segs {
+		base := uint64(seg.Address)
+		end := base + uint64(len(seg.Bytes))
+		i := sort.Search(len(cuts), func(i int) bool {
+			return cuts[i] > base
+		})
+		for addr := base; addr < end; {
+			next := end
+			if i < len(cuts) && cuts[i] < end {
+				next = cuts[i]
+			}
+			region := MapRegion{Record: Record{
+				Address: uint32(addr),
+				Bytes:   seg.Bytes[addr-base : next-base],
+			}}
+			if s, sym, ok := m.Lookup(uint32(addr)); ok {
+				region.Section, region.Symbol = s.Name, sym.Name
+			}
+			regions = append(regions, region)
+			addr = next
+			for i < len(cuts) && cuts[i] <= addr {
+				i++
+			}
+		}
+	}
+	return regions
+}
+
+// Annotate names the symbol and section that contain addr, such as
+// "main+0x4 (.text)", so that a LinkerMap can be used as an Annotator
+// for Listing. It returns "" if no section contains addr.
+func (m *LinkerMap) Annotate(addr uint32, n int) string {
+	section, symbol, ok := m.Lookup(addr)
+	switch {
+	case !ok:
+		return ""
+	case symbol.Name == "":
+		return section.Name
+	case symbol.Address == addr:
+		return fmt.Sprintf("%s (%s)", symbol.Name, section.Name)
+	}
+	return fmt.Sprintf("%s+%#x (%s)", symbol.Name, addr-symbol.Address,
+		section.Name)
+}
@@ -0,0 +1,156 @@
+package ihex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const gnuMap = `Archive member included to satisfy reference by file (symbol)
+
+Memory Configuration
+
+Name             Origin             Length             Attributes
+FLASH            0x08000000         0x00010000         xr
+*default*        0x00000000         0xffffffff
+
+Linker script and memory map
+
+LOAD startup.o
+LOAD main.o
+
+.isr_vector     0x08000000       0x10
+                0x08000000                . = ALIGN (0x4)
+ *(.isr_vector)
+ .isr_vector    0x08000000       0x10 startup.o
+                0x08000000                g_pfnVectors
+
+.text           0x08000010       0x60
+ *(.text*)
+ .text.Reset_Handler
+                0x08000010       0x20 startup.o
+                0x08000010                Reset_Handler
+ .text.main     0x08000030       0x3e main.o
+                0x08000030                main
+                0x08000050                helper
+ *fill*         0x0800006e        0x2 
+                0x08000070                _etext = .
+
+.data           0x20000000        0x4 load address 0x08000070
+ .data          0x20000000        0x4 main.o
+                0x20000000                counter
+
+.comment        0x00000000       0x33
+ .comment       0x00000000       0x33 main.o
+OUTPUT(fw.elf elf32-littlearm)
+`
+
+const lldMap = `             VMA              LMA     Size Align Out     In      Symbol
+          200000           200000       30     4 .text
+          200000           200000       20     4         main.o:(.text)
+          200000           200000        0     1                 main
+          200010           200000        0     1                 helper
+          200020           200020       10     4         lib.a(util.o):(.text)
+          200020           200020        0     1                 util
+          200040           200040        4     4 .data
+          200040           200040        4     1         main.o:(.data)
+          200040           200040        0     1                 counter
+               0                0       1a     1 .comment
+               0                0       1a     1         <internal>:(.comment)
+`
+
+func TestReadLinkerMapGNU(t *testing.T) {
+	m, err := ReadLinkerMap(strings.NewReader(gnuMap))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(m.Sections) != 3 || len(m.InputSections) != 4 ||
+		len(m.Symbols) != 5 {
+		t.Fatal("incorrect map", m)
+	}
+	if len(m.Memory) != 1 ||
+		m.Memory[0] != (MemoryRegion{"FLASH", 0x08000000, 0x10000}) {
+		t.Error("incorrect memory map", m.Memory)
+	}
+	in := m.InputSections[1]
+	if in.Name != ".text.Reset_Handler" || in.Address != 0x08000010 ||
+		in.Size != 0x20 || in.File != "startup.o" {
+		t.Error("incorrect input section", in)
+	}
+	var cases = []struct {
+		addr    uint32
+		section string
+		symbol  string
+	}{
+		{0x08000004, ".isr_vector", "g_pfnVectors"},
+		{0x08000010, ".text", "Reset_Handler"},
+		{0x0800004f, ".text", "main"},
+		{0x0800006f, ".text", "helper"},
+		{0x20000003, ".data", "counter"},
+	}
+	for _, c := range cases {
+		s, sym, ok := m.Lookup(c.addr)
+		if !ok || s.Name != c.section || sym.Name != c.symbol {
+			t.Errorf("%X: expected %s %s, got %v %v",
+				c.addr, c.section, c.symbol, s, sym)
+		}
+	}
+	if _, _, ok := m.Lookup(0x08000070); ok {
+		t.Error("unexpected section at 08000070")
+	}
+}
+
+func TestReadLinkerMapLLD(t *testing.T) {
+	m, err := ReadLinkerMap(strings.NewReader(lldMap))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(m.Sections) != 2 || len(m.InputSections) != 3 ||
+		len(m.Symbols) != 4 {
+		t.Fatal("incorrect map", m)
+	}
+	in := m.InputSections[1]
+	if in.Name != ".text" || in.File != "lib.a(util.o)" {
+		t.Error("incorrect input section", in)
+	}
+	s, sym, ok := m.Lookup(0x200024)
+	if !ok || s.Name != ".text" || sym.Name != "util" {
+		t.Error("incorrect lookup", s, sym)
+	}
+
+	for _, input := range []string{"garbage\n", "VMA LMA\n"} {
+		if _, err := ReadLinkerMap(strings.NewReader(input)); err == nil {
+			t.Errorf("missed invalid map %q", input)
+		}
+	}
+}
+
+func TestImageLabel(t *testing.T) {
+	m, err := ReadLinkerMap(strings.NewReader(gnuMap))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var img Image
+	img.WriteAt(make([]byte, 0x28), 0x0800000c)
+	img.WriteAt(make([]byte, 0x8), 0x0800006c)
+	type region struct {
+		addr    uint32
+		size    int
+		section string
+		symbol  string
+	}
+	want := []region{
+		{0x0800000c, 4, ".isr_vector", "g_pfnVectors"},
+		{0x08000010, 0x20, ".text", "Reset_Handler"},
+		{0x08000030, 4, ".text", "main"},
+		{0x0800006c, 4, ".text", "helper"},
+		{0x08000070, 4, "", ""},
+	}
+	var got []region
+	for _, r := range img.Label(m) {
+		got = append(got, region{r.Address, len(r.Bytes), r.Section, r.Symbol})
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,116 @@
+package ihex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+var recordNames = [...]string{
+	"Data",
+	"End Of File",
+	"Extended Segment Address",
+	"Start Segment Address",
+	"Extended Linear Address",
+	"Start Linear Address",
+}
+
+// An Annotator supplies notes, such as symbol names or disassembly, for
+// the data records in a listing.
+type Annotator interface {
+	// Annotate returns a note for the n bytes of data starting at addr,
+	// or "" for none.
+	Annotate(addr uint32, n int) string
+}
+
+// An AnnotatorFunc is a function that can be used as an Annotator.
+type AnnotatorFunc func(addr uint32, n int) string
+
+// Annotate calls f(addr, n).
+func (f AnnotatorFunc) Annotate(addr uint32, n int) string {
+	return f(addr, n)
+}
+
+// A ListingOption configures Listing.
+type ListingOption func(*listing)
+
+type listing struct {
+	annotators []Annotator
+}
+
+// Annotate adds the notes from a to the end of the line listing each
+// data record, after a semicolon. If more than one Annotator is given,
+// their notes are listed in order.
+func Annotate(a Annotator) ListingOption {
+	return func(l *listing) {
+		l.annotators = append(l.annotators, a)
+	}
+}
+
+// Listing writes an annotated listing of the HEX file read from r to w,
+// with one line for each record giving its line number, type, decoded
+// fields, length, and checksum. A record with an invalid checksum is
+// listed and marked as failing instead of stopping the listing; lines
+// that are not records are listed as they are. It returns the first
+// error that stops parsing.
+func Listing(w io.Writer, r io.Reader, opts ...ListingOption) error {
+	var l listing
+	for _, opt := range opts {
+		opt(&l)
+	}
+	bw := bufio.NewWriter(w)
+	p := NewParser(r, Tee(), NonRecordLines(SkipLines), IgnoreChecksums())
+	listed := false
+	p.onRecord = func(rectyp, reclen byte) {
+		listed = true
+		stored := p.field[255]
+		check := fmt.Sprintf("%02X ok", stored)
+		if p.sum != 0 {
+			check = fmt.Sprintf("%02X FAIL (computed %02X)",
+				stored, stored-p.sum)
+		}
+		fmt.Fprintf(bw, "%5d  %02X %-24s  %-18s  %3d  %s", p.line,
+			rectyp, recordNames[rectyp], p.describe(rectyp, reclen),
+			reclen, check)
+		if rectyp == 0 && reclen > 0 {
+			for _, a := range l.annotators {
+				if note := a.Annotate(p.data.Address, int(reclen)); note != "" {
+					fmt.Fprintf(bw, "  ; %s", note)
+				}
+			}
+		}
+		bw.WriteString("\n")
+	}
+	for p.Parse() {
+		if text := bytes.TrimRight(p.Line(), "\r\n"); !listed && len(text) > 0 {
+			fmt.Fprintf(bw, "%5d  -- %s\n", p.line, text)
+		}
+		listed = false
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return p.Err()
+}
+
+// describe returns the decoded fields of the record just parsed.
+func (p *Parser) describe(rectyp, reclen byte) string {
+	switch rectyp {
+	case 0:
+		start := p.data.Address
+		if reclen == 0 {
+			return fmt.Sprintf("%08X", start)
+		}
+		return fmt.Sprintf("%08X-%08X", start, start+uint32(reclen)-1)
+	case 2:
+		return fmt.Sprintf("base %08X", p.sba)
+	case 3:
+		return fmt.Sprintf("CS:IP %04X:%04X", p.cs, p.ip)
+	case 4:
+		return fmt.Sprintf("base %08X", p.lba)
+	case 5:
+		return fmt.Sprintf("EIP %08X", p.eip)
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package ihex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestListing(t *testing.T) {
+	records := `; banner
+:020000040001F9
+:020010000102EB
+:020012000304E6
+:0400000500010203F1
+:00000001FF
+`
+	var b strings.Builder
+	if err := Listing(&b, strings.NewReader(records)); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `    1  -- ; banner
+    2  04 Extended Linear Address   base 00010000         2  F9 ok
+    3  00 Data                      00010010-00010011     2  EB ok
+    4  00 Data                      00010012-00010013     2  E6 FAIL (computed E5)
+    5  05 Start Linear Address      EIP 00010203          4  F1 ok
+    6  01 End Of File                                     0  FF ok
+`
+	if b.String() != want {
+		t.Errorf("expected listing\n%s\ngot\n%s", want, b.String())
+	}
+
+	err := Listing(&b, strings.NewReader(":020010000102EB\n"))
+	if err == nil || err.Error() != "line 1: missing end record" {
+		t.Errorf("expected missing end record error, got %v", err)
+	}
+}
+
+func TestListingAnnotate(t *testing.T) {
+	m, err := ReadLinkerMap(strings.NewReader(gnuMap))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	records := ":020000040800F2\n:0400340000000000C8\n:00000001FF\n"
+	var b strings.Builder
+	count := AnnotatorFunc(func(addr uint32, n int) string {
+		return fmt.Sprintf("%d bytes", n)
+	})
+	err = Listing(&b, strings.NewReader(records), Annotate(m), Annotate(count))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := "    2  00 Data                      08000034-08000037     4  C8 ok" +
+		"  ; main+0x4 (.text)  ; 4 bytes\n"
+	if lines := strings.SplitAfter(b.String(), "\n"); len(lines) < 2 || lines[1] != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
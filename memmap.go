@@ -0,0 +1,123 @@
+package ihex
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A MemoryRegion is a named range of target memory, such as flash or
+// RAM, as listed in the MEMORY command of a linker script.
+type MemoryRegion struct {
+	Name   string
+	Origin uint32
+	Length uint32
+}
+
+// end returns the address after the last in the MemoryRegion.
+func (r MemoryRegion) end() uint64 {
+	return uint64(r.Origin) + uint64(r.Length)
+}
+
+// A MemoryMap lists the regions of a target's memory. It can be read
+// from a GNU linker map file by ReadLinkerMap, or written out in full.
+type MemoryMap []MemoryRegion
+
+// A RegionCoverage reports how much of a MemoryRegion holds data.
+type RegionCoverage struct {
+	MemoryRegion
+	Used uint32 // bytes holding data
+	Free uint32 // bytes without data
+}
+
+// Percent returns the percentage of the region that holds data.
+func (c RegionCoverage) Percent() float64 {
+	if c.Length == 0 {
+		return 0
+	}
+	return 100 * float64(c.Used) / float64(c.Length)
+}
+
+// Coverage reports how much of each region of m holds data in img, in
+// the order of m, so that the free space in each can be tracked from
+// one release to the next.
+func Coverage(img *Image, m MemoryMap) []RegionCoverage {
+	cov := make([]RegionCoverage, len(m))
+	for i, r := range m {
+		cov[i].MemoryRegion = r
+		var used uint64
+		for _, seg := range img.segs {
+			lo := max(uint64(seg.Address), uint64(r.Origin))
+			hi := min(uint64(seg.Address)+uint64(len(seg.Bytes)), r.end())
+			if lo < hi {
+				used += hi - lo
+			}
+		}
+		cov[i].Used = uint32(used)
+		cov[i].Free = r.Length - uint32(used)
+	}
+	return cov
+}
+
+// region returns the index of the first region of m that holds addr,
+// or -1 if none does.
+func (m MemoryMap) region(addr uint64) int {
+	for i, r := range m {
+		if addr >= uint64(r.Origin) && addr < r.end() {
+			return i
+		}
+	}
+	return -1
+}
+
+// split returns the data of img within each region of m, in the order
+// of m, with nil for a region that holds no data. Data within more than
+// one region belongs to the first. It is an error for img to hold data
+// outside every region.
+func (m MemoryMap) split(img *Image) ([]*Image, error) {
+	imgs := make([]*Image, len(m))
+	for _, seg := range img.segs {
+		base := uint64(seg.Address)
+		end := base + uint64(len(seg.Bytes))
+		for addr := base; addr < end; {
+			i := m.region(addr)
+			if i < 0 {
+				return nil, fmt.Errorf("data at %08X outside of memory map",
+					addr)
+			}
+			hi := min(end, m[i].end())
+			// stop at the start of an earlier region, which takes over
+			for _, r := range m[:i] {
+				if uint64(r.Origin) > addr {
+					hi = min(hi, uint64(r.Origin))
+				}
+			}
+			if imgs[i] == nil {
+				imgs[i] = &Image{}
+			}
+			imgs[i].segs.add(Record{uint32(addr),
+				bytes.Clone(seg.Bytes[addr-base : hi-base])})
+			addr = hi
+		}
+	}
+	return imgs, nil
+}
+
+// SplitByMap returns the data in the Image within each region of m,
+// keyed by the name of the region, so that one build can be written as
+// the set of files that each programmer expects. Regions that hold no
+// data are left out, and data within more than one region belongs to
+// the first. It is an error for the Image to hold data outside every
+// region. The start address is not copied.
+func (img *Image) SplitByMap(m MemoryMap) (map[string]*Image, error) {
+	imgs, err := m.split(img)
+	if err != nil {
+		return nil, err
+	}
+	parts := make(map[string]*Image)
+	for i, part := range imgs {
+		if part != nil {
+			parts[m[i].Name] = part
+		}
+	}
+	return parts, nil
+}
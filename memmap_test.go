@@ -0,0 +1,66 @@
+package ihex
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	var img Image
+	img.WriteAt(make([]byte, 0x100), 0x0ff0)
+	img.WriteAt(make([]byte, 0x10), 0x3000)
+	m := MemoryMap{
+		{"boot", 0, 0x1000},
+		{"app", 0x1000, 0x3000},
+		{"eeprom", 0x8000, 0x100},
+	}
+	got := Coverage(&img, m)
+	want := []RegionCoverage{
+		{m[0], 0x10, 0xff0},
+		{m[1], 0x100, 0x2f00},
+		{m[2], 0, 0x100},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if p := got[1].Percent(); p < 2.08 || p > 2.09 {
+		t.Errorf("expected 2.08%%, got %v", p)
+	}
+	if p := (RegionCoverage{}).Percent(); p != 0 {
+		t.Errorf("expected 0%% of an empty region, got %v", p)
+	}
+}
+
+func TestSplitByMap(t *testing.T) {
+	var img Image
+	img.WriteAt([]byte{1, 2, 3, 4}, 0x0ffe)
+	img.WriteAt([]byte{5}, 0x2000)
+	m := MemoryMap{
+		{"boot", 0, 0x1000},
+		{"app", 0x1000, 0x1000},
+		{"eeprom", 0x8000, 0x100},
+		{"all", 0, 0x10000},
+	}
+	parts, err := img.SplitByMap(m)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got := fmt.Sprint(parts["boot"].Segments(), parts["app"].Segments(),
+		parts["all"].Segments())
+	if want := "[{4094 [1 2]}] [{4096 [3 4]}] [{8192 [5]}]"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if len(parts) != 3 {
+		t.Errorf("expected 3 parts, got %d", len(parts))
+	}
+	// later changes to the parts do not affect the Image
+	parts["boot"].WriteAt([]byte{9}, 0x0ffe)
+	if b := img.Bytes(0)[0]; b != 1 {
+		t.Error("part shares data with the Image")
+	}
+
+	_, err = img.SplitByMap(m[:2])
+	if err == nil || err.Error() != "data at 00002000 outside of memory map" {
+		t.Errorf("expected data outside of memory map, got %v", err)
+	}
+}
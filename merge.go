@@ -0,0 +1,131 @@
+package ihex
+
+import (
+	"errors"
+	"io"
+)
+
+// A ConflictPolicy determines what happens when data being merged
+// writes a different value to an address that already holds data. Data
+// that writes the same value is an identical overlap, which only
+// OverlapError treats as an error.
+type ConflictPolicy int
+
+const (
+	ConflictError ConflictPolicy = iota // stop with an error (the default)
+	FirstWins                           // keep the data already held
+	LastWins                            // replace it with the new data
+	OverlapError                        // stop with an error at any overlap
+)
+
+// Merge adds the data and any start address from src to the Image,
+// resolving any conflicts according to policy. Data that is the same in
+// both is not a conflict, and is only an error with OverlapError. A
+// start address is not a conflict unless the policy is ConflictError or
+// OverlapError and the addresses differ. With either of those policies,
+// the Image is unchanged if there is an error.
+func (img *Image) Merge(src *Image, policy ConflictPolicy) error {
+	recs := src.segs
+	if policy != LastWins {
+		recs = nil
+		for _, seg := range src.segs {
+			if policy == OverlapError {
+				if err := img.segs.overlapError(seg); err != nil {
+					return err
+				}
+			}
+			missing, err := img.segs.dedupe(seg, policy == ConflictError)
+			if err != nil {
+				return err
+			}
+			recs = append(recs, missing...)
+		}
+	}
+	start, err := mergeStart(img.start, src.start, policy)
+	if err != nil {
+		return err
+	}
+	for _, r := range recs {
+		img.segs.add(r)
+	}
+	img.start = start
+	return nil
+}
+
+// mergeStart returns the start address of an Image that has start
+// address dst after merging one that has src, according to policy.
+func mergeStart(dst, src *startAddr, policy ConflictPolicy) (*startAddr, error) {
+	switch {
+	case src == nil:
+	case dst == nil || policy == LastWins:
+		return src, nil
+	case (policy == ConflictError || policy == OverlapError) && *src != *dst:
+		return nil, errors.New("conflicting start address")
+	}
+	return dst, nil
+}
+
+// WriteImage writes data records holding each segment of img, followed
+// by a start address record if img has a start address.
+func (w *Writer) WriteImage(img *Image) error {
+	for _, seg := range img.segs {
+		if err := w.WriteData(seg.Address, seg.Bytes); err != nil {
+			return err
+		}
+	}
+	return w.writeStartAddr(img.start)
+}
+
+// Merge combines the Intel HEX files read from srcs into a single file
+// written to dst, in which data from later sources is merged into data
+// from earlier ones according to policy. A start address record is
+// written if any source has one; different start addresses are a
+// conflict, like different data.
+func Merge(dst io.Writer, srcs []io.Reader, policy ConflictPolicy) error {
+	img := &Image{}
+	for i, r := range srcs {
+		src, err := ReadImage(r)
+		if err != nil {
+			return SourceError{Source: i, Err: err}
+		}
+		if err := img.Merge(src, policy); err != nil {
+			return SourceError{Source: i, Err: err}
+		}
+	}
+	w := NewWriter(dst)
+	if err := w.WriteImage(img); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// A startAddr holds the start address from a record of type 3 or 5.
+type startAddr struct {
+	linear bool // whether eip is set, rather than cs and ip
+	eip    uint32
+	cs, ip uint16
+}
+
+// readStart returns the start address read by p, preferring one from a
+// record of type 5, or nil if p read neither type.
+func readStart(p *Parser) *startAddr {
+	if eip, ok := p.EIP(); ok {
+		return &startAddr{linear: true, eip: eip}
+	}
+	if cs, ip, ok := p.CSIP(); ok {
+		return &startAddr{cs: cs, ip: ip}
+	}
+	return nil
+}
+
+// writeStartAddr writes the record for s, if it is not nil.
+func (w *Writer) writeStartAddr(s *startAddr) error {
+	switch {
+	case s == nil:
+		return nil
+	case s.linear:
+		return w.WriteStart(s.eip)
+	default:
+		return w.WriteCSIP(s.cs, s.ip)
+	}
+}
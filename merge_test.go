@@ -0,0 +1,62 @@
+package ihex
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	const (
+		a = ":03000000010203F7\n:0400000500000100F6\n:00000001FF\n"
+		b = ":020001000209F2\n:0400000500000200F5\n:00000001FF\n"
+		c = ":0100010002FC\n:00000001FF\n"
+	)
+	tests := []struct {
+		srcs   []string
+		policy ConflictPolicy
+		data   []byte
+		eip    uint32
+		err    string
+	}{
+		{[]string{a, c}, ConflictError, []byte{1, 2, 3}, 0x100, ""},
+		{[]string{a, b}, ConflictError, nil, 0,
+			"input 1: conflicting data at 00000002"},
+		{[]string{a, b}, FirstWins, []byte{1, 2, 3}, 0x100, ""},
+		{[]string{a, b}, LastWins, []byte{1, 2, 9}, 0x200, ""},
+		{[]string{a, c}, OverlapError, nil, 0,
+			"input 1: identical overlapping data at 00000001"},
+		{[]string{a, b}, OverlapError, nil, 0,
+			"input 1: conflicting data at 00000002"},
+	}
+	for i, tt := range tests {
+		var srcs []io.Reader
+		for _, s := range tt.srcs {
+			srcs = append(srcs, strings.NewReader(s))
+		}
+		var out strings.Builder
+		err := Merge(&out, srcs, tt.policy)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%d: expected error %q, got %v", i, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: unexpected error %v", i, err)
+		}
+		p := ParseString(out.String())
+		var got []Record
+		for p.Parse() {
+			got = append(got, Record{p.Data().Address,
+				append([]byte(nil), p.Data().Bytes...)})
+		}
+		if p.Err() != nil {
+			t.Fatalf("%d: unexpected error %v", i, p.Err())
+		}
+		checkRecords(t, got, []Record{{0, tt.data}})
+		if eip, ok := p.EIP(); !ok || eip != tt.eip {
+			t.Errorf("%d: expected start %X, got %X", i, tt.eip, eip)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ihex
+
+import (
+	"encoding/json"
+	"expvar"
+	"time"
+)
+
+// Metrics holds counters describing the work done by a Parser or a
+// Writer.
+type Metrics struct {
+	Records  int           // records parsed or written, of any type
+	Bytes    int           // data bytes decoded or encoded
+	Errors   int           // errors encountered
+	Warnings int           // warnings recorded, by a Parser only
+	Elapsed  time.Duration // wall time from the first record
+}
+
+// Metrics returns the counters for the records parsed so far. Elapsed
+// stops increasing once Parse has returned false.
+func (p *Parser) Metrics() Metrics {
+	m := Metrics{
+		Records:  p.nrec,
+		Bytes:    p.nbytes,
+		Warnings: len(p.warnings),
+		Elapsed:  p.elapsed,
+	}
+	if len(p.errors) > 0 {
+		m.Errors = len(p.errors)
+	} else if p.err != nil {
+		m.Errors = 1
+	}
+	if m.Elapsed == 0 && !p.start.IsZero() {
+		m.Elapsed = time.Since(p.start)
+	}
+	return m
+}
+
+// Metrics returns the counters for the records written so far. Elapsed
+// runs from the first record written and stops increasing once Close
+// has been called.
+func (w *Writer) Metrics() Metrics {
+	m := Metrics{
+		Records: w.nrec,
+		Bytes:   w.nbytes,
+		Elapsed: w.elapsed,
+	}
+	if w.err != nil {
+		m.Errors = 1
+	}
+	if m.Elapsed == 0 && !w.start.IsZero() {
+		m.Elapsed = time.Since(w.start)
+	}
+	return m
+}
+
+// Publish adds the counters in m to those in v, so that totals over
+// many runs can be exported by a variable published with expvar. The
+// keys used are "records", "bytes", "errors", "warnings", and
+// "elapsed_ns".
+func (m Metrics) Publish(v *expvar.Map) {
+	v.Add("records", int64(m.Records))
+	v.Add("bytes", int64(m.Bytes))
+	v.Add("errors", int64(m.Errors))
+	v.Add("warnings", int64(m.Warnings))
+	v.Add("elapsed_ns", int64(m.Elapsed))
+}
+
+// String returns m as a JSON object, so that a Metrics satisfies
+// expvar.Var.
+func (m Metrics) String() string {
+	b, _ := json.Marshal(m)
+	return string(b)
+}
@@ -0,0 +1,66 @@
+package ihex
+
+import (
+	"expvar"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	records := `
+:0B0010006164647265737320676170A7
+:020000021200EA
+banner
+:0B0010006164647265737320676170A7
+:00000001FF
+`
+	p := NewParser(strings.NewReader(records), NonRecordLines(WarnLines))
+	for p.Parse() {
+	}
+	m := p.Metrics()
+	if m.Records != 4 || m.Bytes != 22 || m.Errors != 0 || m.Warnings != 1 {
+		t.Error("incorrect metrics", m)
+	}
+	if m.Elapsed <= 0 || p.Metrics().Elapsed != m.Elapsed {
+		t.Error("incorrect elapsed time", m.Elapsed)
+	}
+
+	v := new(expvar.Map).Init()
+	m.Publish(v)
+	p = NewParser(strings.NewReader(":00000001FE"))
+	for p.Parse() {
+	}
+	p.Metrics().Publish(v)
+	if v.Get("records").String() != "4" || v.Get("errors").String() != "1" {
+		t.Error("incorrect published metrics", v)
+	}
+}
+
+func TestWriterMetrics(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b)
+	if m := w.Metrics(); m != (Metrics{}) {
+		t.Error("expected no metrics before writing", m)
+	}
+	w.WriteData(0xfff8, make([]byte, 20))
+	w.WriteStart(0x100)
+	w.Close()
+	m := w.Metrics()
+	// a data record on each side of a base record, a start record and
+	// an end record
+	if m.Records != 5 || m.Bytes != 20 || m.Errors != 0 {
+		t.Error("incorrect metrics", m)
+	}
+	if m.Elapsed <= 0 || w.Metrics().Elapsed != m.Elapsed {
+		t.Error("incorrect elapsed time", m.Elapsed)
+	}
+
+	v := new(expvar.Map).Init()
+	m.Publish(v)
+	w = NewWriter(&b, SegmentAddressing())
+	w.WriteData(0xffff0, make([]byte, 32))
+	w.Metrics().Publish(v)
+	if v.Get("records").String() != "5" || v.Get("errors").String() != "1" {
+		t.Error("incorrect published metrics", v)
+	}
+}
@@ -0,0 +1,94 @@
+package ihex
+
+import (
+	"fmt"
+	"io"
+)
+
+// A MultiParser reads the data records from a sequence of inputs as a
+// single stream. Each input is a complete Intel HEX file, with its own
+// end record, and is parsed by its own Parser, so any segment or linear
+// base address from one input does not apply to the next.
+type MultiParser struct {
+	readers []io.Reader
+	opts    []Option
+	p       *Parser
+	source  int
+	err     error
+}
+
+// A SourceError is an error from one of the inputs of a MultiParser.
+type SourceError struct {
+	Source int // index of the input
+	Err    error
+}
+
+func (e SourceError) Error() string {
+	return fmt.Sprintf("input %d: %v", e.Source, e.Err)
+}
+
+func (e SourceError) Unwrap() error {
+	return e.Err
+}
+
+// NewMultiParser returns a new MultiParser to read from each of readers
+// in turn, with a Parser for each input created with opts.
+func NewMultiParser(readers []io.Reader, opts ...Option) *MultiParser {
+	return &MultiParser{readers: readers, opts: opts, source: -1}
+}
+
+// Parse reads the next data record, which can then be accessed by the
+// Data method and attributed to an input by the Source method. It
+// returns false when there are no more data records in any input, or if
+// an error occurred during parsing.
+func (m *MultiParser) Parse() bool {
+	for m.err == nil {
+		if m.p != nil {
+			if m.p.Parse() {
+				if !m.p.HasData() {
+					continue
+				}
+				return true
+			}
+			if err := m.p.Err(); err != nil {
+				m.err = SourceError{Source: m.source, Err: err}
+				return false
+			}
+		}
+		if m.source+1 >= len(m.readers) {
+			return false
+		}
+		m.source++
+		m.p = NewParser(m.readers[m.source], m.opts...)
+	}
+	return false
+}
+
+// Data returns the last record read by the Parse method, or an empty
+// Record if Parse has not been called. The underlying data may be
+// overwritten by subsequent calls to Parse.
+func (m *MultiParser) Data() Record {
+	if m.p == nil {
+		return Record{}
+	}
+	return m.p.Data()
+}
+
+// Source returns the index of the input from which the last record was
+// read.
+func (m *MultiParser) Source() int {
+	return m.source
+}
+
+// Parser returns the Parser for the current input, from which
+// information such as start addresses or warnings can be accessed. It
+// returns nil if Parse has not been called.
+func (m *MultiParser) Parser() *Parser {
+	return m.p
+}
+
+// Err returns the first error that was encountered by the MultiParser,
+// as a SourceError.
+func (m *MultiParser) Err() error {
+	return m.err
+}
@@ -0,0 +1,56 @@
+package ihex
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMultiParser(t *testing.T) {
+	m := NewMultiParser([]io.Reader{
+		strings.NewReader(`
+:020000021200EA
+:0B0010006164647265737320676170A7
+:00000001FF
+`),
+		strings.NewReader(`
+:0B0010006164647265737320676170A7
+:00000001FF
+`),
+		strings.NewReader(`
+:0B0010006164647265737320676170A7
+:00000001FE
+`),
+	})
+	if r := m.Data(); r.Address != 0 || r.Bytes != nil || m.Parser() != nil {
+		t.Error("expected no record before Parse", r)
+	}
+	addrs := []uint32{0x12010, 0x10, 0x10}
+	sources := []int{0, 1, 2}
+	n := 0
+	for m.Parse() {
+		if m.Data().Address != addrs[n] || m.Source() != sources[n] {
+			t.Error("incorrect record", n, m.Data().Address, m.Source())
+		}
+		n++
+	}
+	if n != 3 {
+		t.Error("expected 3 records, got", n)
+	}
+	var serr SourceError
+	if !errors.As(m.Err(), &serr) || serr.Source != 2 {
+		t.Fatal("incorrect error", m.Err())
+	}
+	want := "input 2: line 3: invalid checksum: stored FE, computed FF"
+	if m.Err().Error() != want {
+		t.Error("incorrect error message", m.Err())
+	}
+
+	m = NewMultiParser([]io.Reader{strings.NewReader("")})
+	m.Parse()
+	if m.Err() == nil ||
+		m.Err().Error() != "input 0: line 0: missing end record" {
+		t.Error("missed missing end record", m.Err())
+	}
+}
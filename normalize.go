@@ -0,0 +1,64 @@
+package ihex
+
+import "io"
+
+// Normalize reads the Intel HEX file from r with a Parser configured by
+// any options given, and writes it to w in canonical form: the data is
+// sorted by address, with overlapping data resolved by the last write,
+// and written in records of 16 bytes with correct checksums and only
+// the extended address records that are needed. Checksums in the input
+// are ignored, so Normalize can be used to repair them. Extended
+// segment address records are written if the input used them and not
+// extended linear address records, and the data allows it.
+func Normalize(r io.Reader, w io.Writer, opts ...Option) error {
+	return NormalizeTo(r, NewWriter(w), opts...)
+}
+
+// NormalizeTo is like Normalize, but writes with hw, which it closes,
+// so that the output can be configured by WriterOptions such as
+// RecordLength.
+func NormalizeTo(r io.Reader, hw *Writer, opts ...Option) error {
+	opts = append(append([]Option(nil), opts...), IgnoreChecksums())
+	p := NewParser(r, opts...)
+	var types [256]bool
+	p.onRecord = func(rectyp, reclen byte) {
+		types[rectyp] = true
+	}
+	img := &Image{}
+	if err := img.Load(p); err != nil {
+		return err
+	}
+	if types[2] && !types[4] && img.fitsSegmented() {
+		hw.segment = true
+	}
+	if err := hw.WriteImage(img); err != nil {
+		return err
+	}
+	return hw.Close()
+}
+
+// WriteCanonical writes the Image to w in canonical form, which is
+// meant for files kept under version control, where the same data
+// should always give the same text. The data is written in order of
+// address, in records of 16 bytes, with uppercase digits, lines ending
+// in a line feed, and only the extended linear address records that
+// are needed; the start address, if any, comes after the data, followed
+// by the end record. The canonical form will not change in future
+// versions of this package.
+func WriteCanonical(w io.Writer, img *Image) error {
+	hw := NewWriter(w, Canonical())
+	if err := hw.WriteImage(img); err != nil {
+		return err
+	}
+	return hw.Close()
+}
+
+// fitsSegmented reports whether all of the data in the Image is within
+// the 1MB segmented address space.
+func (img *Image) fitsSegmented() bool {
+	if len(img.segs) == 0 {
+		return true
+	}
+	last := img.segs[len(img.segs)-1]
+	return uint64(last.Address)+uint64(len(last.Bytes)) <= 1<<20
+}
@@ -0,0 +1,93 @@
+package ihex
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	input := `:020000021000EC
+:020004000506EF
+:020000021000EC
+:0400000001020304F0
+:0400000300001234B3
+:00000001FF
+`
+	var b strings.Builder
+	if err := Normalize(strings.NewReader(input), &b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `:020000021000EC
+:06000000010203040506E5
+:0400000300001234B3
+:00000001FF
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+
+	err := Normalize(strings.NewReader(":0400000001020304F0\n"), &b)
+	if err == nil || err.Error() != "line 1: missing end record" {
+		t.Errorf("expected missing end record error, got %v", err)
+	}
+
+	b.Reset()
+	input = ":0400000001020304F2\n:0400040005060708DE\n:00000001FF\n"
+	hw := NewWriter(&b, RecordLength(8), LowerCase())
+	if err := NormalizeTo(strings.NewReader(input), hw); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want = ":080000000102030405060708d4\n:00000001ff\n"
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+
+	// the caller's options must not be overwritten
+	opts := make([]Option, 1, 2)
+	opts[0] = NonRecordLines(SkipLines)
+	spare := opts[:2]
+	Normalize(strings.NewReader(input), io.Discard, opts...)
+	if spare[1] != nil {
+		t.Error("Normalize wrote to the caller's options")
+	}
+}
+
+func TestWriteCanonical(t *testing.T) {
+	img := &Image{}
+	img.WriteAt([]byte{0xaa}, 0x10000)
+	data := make([]byte, 18)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	img.WriteAt(data, 0)
+	img.SetStartLinear(0x100)
+	want := `:10000000000102030405060708090A0B0C0D0E0F78
+:020010001011CD
+:020000040001F9
+:01000000AA55
+:0400000500000100F6
+:00000001FF
+`
+	var b strings.Builder
+	if err := WriteCanonical(&b, img); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+
+	// Canonical overrides the options before it
+	b.Reset()
+	hw := NewWriter(&b, LowerCase(), RecordLength(4), CRLF(),
+		SegmentAddressing(), AlwaysWriteBase(), Canonical())
+	if err := hw.WriteImage(img); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := hw.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,59 @@
+package ihex
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// WriteOpenOCD writes an OpenOCD script to dst under name, with the
+// files it programs written beside it, so that flashing instructions
+// can be released with the firmware. Each region of m that holds data
+// in img is written as a binary file spanning that data, with gaps
+// filled with 0xFF, and named after the script and the region, such as
+// "flash-FLASH.bin" for a script named "flash.tcl". If m is nil, each
+// segment of img is written as a file named after its address instead.
+// The script erases and writes each file with flash write_image, then
+// checks it with verify_image, and is meant to be run after init and
+// reset halt. It is an error for img to hold data outside every region
+// of m.
+func WriteOpenOCD(dst OutputFS, name string, img *Image, m MemoryMap) error {
+	type part struct {
+		file string
+		img  *Image
+	}
+	stem := strings.TrimSuffix(name, path.Ext(name))
+	var parts []part
+	if m == nil {
+		for _, seg := range img.segs {
+			p := &Image{}
+			p.segs.add(seg)
+			parts = append(parts,
+				part{fmt.Sprintf("%s-%08X.bin", stem, seg.Address), p})
+		}
+	} else {
+		imgs, err := m.split(img)
+		if err != nil {
+			return err
+		}
+		for i, p := range imgs {
+			if p != nil {
+				parts = append(parts,
+					part{fmt.Sprintf("%s-%s.bin", stem, m[i].Name), p})
+			}
+		}
+	}
+	var script strings.Builder
+	script.WriteString("# OpenOCD flash script; run after init and reset halt.\n")
+	for _, p := range parts {
+		if err := dst.WriteFile(p.file, p.img.Bytes(0xff)); err != nil {
+			return err
+		}
+		addr := p.img.segs[0].Address
+		file := path.Base(p.file)
+		fmt.Fprintf(&script, "flash write_image erase {%s} 0x%08X bin\n",
+			file, addr)
+		fmt.Fprintf(&script, "verify_image {%s} 0x%08X bin\n", file, addr)
+	}
+	return dst.WriteFile(name, []byte(script.String()))
+}
@@ -0,0 +1,52 @@
+package ihex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteOpenOCD(t *testing.T) {
+	var img Image
+	img.WriteAt([]byte{1, 2}, 0x08000000)
+	img.WriteAt([]byte{3}, 0x08000004)
+	img.WriteAt([]byte{4, 5}, 0x08080000)
+	m := MemoryMap{
+		{"FLASH", 0x08000000, 0x10000},
+		{"EEPROM", 0x08080000, 0x800},
+		{"RAM", 0x20000000, 0x5000},
+	}
+	dst := memOutput{}
+	if err := WriteOpenOCD(dst, "out/flash.tcl", &img, m); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `# OpenOCD flash script; run after init and reset halt.
+flash write_image erase {flash-FLASH.bin} 0x08000000 bin
+verify_image {flash-FLASH.bin} 0x08000000 bin
+flash write_image erase {flash-EEPROM.bin} 0x08080000 bin
+verify_image {flash-EEPROM.bin} 0x08080000 bin
+`
+	if got := string(dst["out/flash.tcl"]); got != want {
+		t.Errorf("expected\n%s, got\n%s", want, got)
+	}
+	if b := dst["out/flash-FLASH.bin"]; !bytes.Equal(b, []byte{1, 2, 0xff, 0xff, 3}) {
+		t.Errorf("incorrect FLASH data % X", b)
+	}
+	if len(dst) != 3 {
+		t.Errorf("expected 3 files, got %d", len(dst))
+	}
+
+	dst = memOutput{}
+	if err := WriteOpenOCD(dst, "flash.tcl", &img, nil); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(dst) != 4 || !strings.Contains(string(dst["flash.tcl"]),
+		"verify_image {flash-08000004.bin} 0x08000004 bin\n") {
+		t.Errorf("expected a file for each segment, got\n%s", dst["flash.tcl"])
+	}
+
+	err := WriteOpenOCD(memOutput{}, "flash.tcl", &img, m[:1])
+	if err == nil || err.Error() != "data at 08080000 outside of memory map" {
+		t.Errorf("expected data outside of memory map, got %v", err)
+	}
+}
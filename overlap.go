@@ -0,0 +1,130 @@
+package ihex
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// DetectOverlaps makes the Parser stop with an error when a data record
+// writes to an address that an earlier data record already wrote. An
+// overlap that writes the same values as before is reported as
+// "identical overlapping data", and one that changes them as
+// "overlapping data", at the first address that changes.
+func DetectOverlaps() Option {
+	return func(p *Parser) {
+		p.overlaps = &spans{}
+	}
+}
+
+// WarnOverlaps is like DetectOverlaps, but records a warning instead of
+// stopping. Warnings for identical overlaps are logged with the code
+// "overlap-identical", and others with the code "overlap".
+func WarnOverlaps() Option {
+	return func(p *Parser) {
+		p.overlaps = &spans{}
+		p.warnOverlaps = true
+	}
+}
+
+// IgnoreIdenticalOverlaps makes DetectOverlaps and WarnOverlaps ignore
+// data records that only write the same values to addresses that were
+// already written, as tools that repeat shared data often do.
+func IgnoreIdenticalOverlaps() Option {
+	return func(p *Parser) {
+		p.ignoreIdentical = true
+	}
+}
+
+// checkOverlap checks r against the data written so far, then adds r to
+// it.
+func (p *Parser) checkOverlap(r Record) {
+	addr, ok, differs := p.overlaps.overlap(r)
+	p.overlaps.add(r)
+	if !ok || (!differs && p.ignoreIdentical) {
+		return
+	}
+	code, msg := "overlap", fmt.Sprintf("overlapping data at %08X", addr)
+	if !differs {
+		code = "overlap-identical"
+		msg = "identical " + msg
+	}
+	if p.warnOverlaps {
+		p.warn(code, msg, slog.Uint64("address", uint64(addr)))
+	} else if p.err == nil {
+		p.err = p.makeError(msg)
+	}
+}
+
+// overlap reports whether r writes to any address held in s, and if so,
+// whether it writes a different value to any of them. The address
+// returned is the first that r changes, or else the first that it
+// overlaps.
+func (s spans) overlap(r Record) (addr uint32, ok, differs bool) {
+	base := uint64(r.Address)
+	end := base + uint64(len(r.Bytes))
+	for i := s.find(base); i < len(s) && uint64(s[i].Address) < end; i++ {
+		start := uint64(s[i].Address)
+		lo := max(base, start)
+		hi := min(end, start+uint64(len(s[i].Bytes)))
+		if !ok {
+			addr, ok = uint32(lo), true
+		}
+		have := s[i].Bytes[lo-start : hi-start]
+		for j, b := range r.Bytes[lo-base : hi-base] {
+			if b != have[j] {
+				return uint32(lo) + uint32(j), true, true
+			}
+		}
+	}
+	return addr, ok, false
+}
+
+// overlapError returns an error if r writes to any address held in s,
+// saying whether the data it writes there is identical or conflicting.
+func (s spans) overlapError(r Record) error {
+	addr, ok, differs := s.overlap(r)
+	switch {
+	case !ok:
+		return nil
+	case differs:
+		return fmt.Errorf("conflicting data at %08X", addr)
+	}
+	return fmt.Errorf("identical overlapping data at %08X", addr)
+}
+
+// An addrRange is a half-open range of addresses.
+type addrRange struct {
+	start, end uint64
+}
+
+// ranges holds non-overlapping ranges of addresses, sorted by address.
+type ranges []addrRange
+
+// add adds the addresses of r to s. If any of them were already in s,
+// it returns the first such address with ok true.
+func (s *ranges) add(r Record) (addr uint32, ok bool) {
+	if len(r.Bytes) == 0 {
+		return 0, false
+	}
+	start := uint64(r.Address)
+	end := start + uint64(len(r.Bytes))
+	// find the ranges that overlap or touch r
+	i := sort.Search(len(*s), func(i int) bool {
+		return (*s)[i].end >= start
+	})
+	j := i
+	for j < len(*s) && (*s)[j].start <= end {
+		if (*s)[j].start < end && (*s)[j].end > start && !ok {
+			addr, ok = uint32(max(start, (*s)[j].start)), true
+		}
+		j++
+	}
+	if i == j {
+		*s = append((*s)[:i], append([]addrRange{{start, end}}, (*s)[i:]...)...)
+		return addr, ok
+	}
+	(*s)[i] = addrRange{min(start, (*s)[i].start), max(end, (*s)[j-1].end)}
+	*s = append((*s)[:i+1], (*s)[j:]...)
+	return addr, ok
+}
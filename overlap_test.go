@@ -0,0 +1,117 @@
+package ihex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectOverlaps(t *testing.T) {
+	records := `:0100100001EE
+:0100110004EA
+:02000F000203EA
+:00000001FF
+`
+	p := NewParser(strings.NewReader(records), DetectOverlaps())
+	n := 0
+	for p.Parse() {
+		n++
+	}
+	if p.Err() == nil || p.Err().Error() != "line 3: overlapping data at 00000010" {
+		t.Errorf("expected overlap error, got %v", p.Err())
+	}
+	if n != 2 {
+		t.Errorf("expected 2 records before the error, got %d", n)
+	}
+
+	p = NewParser(strings.NewReader(records), WarnOverlaps())
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	w := p.Warnings()
+	if len(w) != 1 || w[0].Error() != "line 3: overlapping data at 00000010" {
+		t.Errorf("expected overlap warning, got %v", w)
+	}
+}
+
+func TestIdenticalOverlaps(t *testing.T) {
+	records := `:0100100001EE
+:0100110004EA
+:02001000010AE3
+:010011000AE4
+:00000001FF
+`
+	p := NewParser(strings.NewReader(records), WarnOverlaps())
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	var got []string
+	for _, w := range p.Warnings() {
+		got = append(got, w.Error())
+	}
+	want := []string{
+		"line 3: overlapping data at 00000011",
+		"line 4: identical overlapping data at 00000011",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected warnings %q, got %q", want, got)
+	}
+
+	p = NewParser(strings.NewReader(records), DetectOverlaps(),
+		IgnoreIdenticalOverlaps())
+	for p.Parse() {
+	}
+	if p.Err() == nil || p.Err().Error() != "line 3: overlapping data at 00000011" {
+		t.Errorf("expected overlap error, got %v", p.Err())
+	}
+
+	// only identical overlaps
+	records = ":0100100001EE\n:0100100001EE\n:00000001FF\n"
+	p = NewParser(strings.NewReader(records), DetectOverlaps())
+	for p.Parse() {
+	}
+	if p.Err() == nil ||
+		p.Err().Error() != "line 2: identical overlapping data at 00000010" {
+		t.Errorf("expected identical overlap error, got %v", p.Err())
+	}
+	p = NewParser(strings.NewReader(records), DetectOverlaps(),
+		IgnoreIdenticalOverlaps())
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Error("unexpected error", p.Err())
+	}
+}
+
+func TestRanges(t *testing.T) {
+	var s ranges
+	for _, r := range []Record{
+		{0x10, []byte{1, 2}},
+		{0x20, []byte{1}},
+		{0x12, []byte{1}},
+		{0x14, []byte{1}},
+	} {
+		if _, ok := s.add(r); ok {
+			t.Errorf("unexpected overlap for %v", r)
+		}
+	}
+	want := ranges{{0x10, 0x13}, {0x14, 0x15}, {0x20, 0x21}}
+	if len(s) != len(want) {
+		t.Fatalf("expected %v, got %v", want, s)
+	}
+	for i := range s {
+		if s[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, s)
+		}
+	}
+	addr, ok := s.add(Record{0x13, []byte{1, 2, 3, 4, 5, 6, 7, 8}})
+	if !ok || addr != 0x14 {
+		t.Errorf("expected overlap at 14, got %X (%v)", addr, ok)
+	}
+	if len(s) != 2 || s[0] != (addrRange{0x10, 0x1b}) {
+		t.Errorf("unexpected ranges %v", s)
+	}
+}
@@ -1,11 +1,19 @@
-// Package ihex implements a parser for Intel HEX files.
+// Package ihex implements a parser and writer for Intel HEX files.
 package ihex
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
+	"time"
+	"unicode/utf8"
 )
 
 // A Record holds the address and data bytes from a data (type 0)
@@ -16,6 +24,15 @@ type Record struct {
 	Bytes   []byte
 }
 
+// A RawRecord holds the fields of a record of any type.
+type RawRecord struct {
+	Type     byte
+	Offset   uint16 // the load offset field
+	Address  uint32 // for a data record, the address of its first byte
+	Data     []byte
+	Checksum byte
+}
+
 // A ParseError represents an error encountered during parsing.
 type ParseError struct {
 	Line int
@@ -26,10 +43,15 @@ func (e ParseError) Error() string {
 	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
 }
 
+// ErrBinaryInput is returned by the Err method of a Parser when its input
+// appears to be binary data rather than text.
+var ErrBinaryInput = errors.New(
+	"input does not look like Intel HEX; did you mean a raw binary?")
+
 // A Parser reads records from an io.Reader, with an interface similar
 // to bufio.Scanner.
 type Parser struct {
-	scanner *bufio.Scanner
+	scanner lineScanner
 	field   [256]byte
 	err     error
 	lba     uint32
@@ -44,14 +66,425 @@ type Parser struct {
 	data    Record
 	wrap    *Record
 	b       []byte
+	rec     []byte
 	line    int
+	offset  int64 // of the start of the last line read
+	next    int64 // of the start of the next line
 	sum     byte
 	ended   bool
+	raw     []byte
+
+	// record holds the bytes of a record decoded by decodeLine, which
+	// sets decoded to the length of its text
+	record     [260]byte
+	decoded    int
+	decodedSum byte
+
+	// crAt is the offset of a carriage return found by indexCR, and
+	// there are no others between it and crSearched
+	crAt       int64
+	crSearched int64
+
+	stopAtEnd bool
+	trailing  []byte
+	lines     LinePolicy
+	warnings  []ParseError
+	utf16     bool
+	gotRecord bool
+	salvage   bool
+	salvaged  bool
+	corrupt   bool // whether the text of the current record is damaged
+	logger    *slog.Logger
+	tee       bool
+	text      []byte
+	hasData   bool
+
+	nrec    int
+	nbytes  int
+	start   time.Time
+	elapsed time.Duration
+
+	// onRecord, if set, is called after each record is parsed, when
+	// any data from the record can be accessed by the Data method.
+	onRecord func(rectyp, reclen byte)
+
+	filter  func(RawRecord) bool
+	dropped bool
+
+	allRecords bool
+	rawRec     RawRecord
+	isRecord   bool
+
+	overlaps        *spans
+	warnOverlaps    bool
+	ignoreIdentical bool
+
+	format FileFormat
+
+	maxLine int
+
+	transforms []Transform
+	queue      []Record
+
+	wordWidth int
+	wordOrder ByteOrder
+
+	collect   bool
+	collected bool // whether the errors have all been collected
+	errors    []ParseError
+	eof       bool
+
+	// ignoreSums makes the Parser accept records with an invalid
+	// checksum, leaving p.sum nonzero after such a record.
+	ignoreSums    bool
+	allowNoEnd    bool
+	allowTrailing bool
+	trimSpace     bool
+
+	trailer     func([]byte) ([]byte, bool)
+	hash        hash.Hash
+	trailerSum  []byte
+	trailerLine int
+	atTrailer   bool
+}
+
+// NewParser returns a new Parser to read from r, configured by any
+// options given.
+func NewParser(r io.Reader, opts ...Option) *Parser {
+	p := newParser(opts)
+	p.setReader(r)
+	return p
+}
+
+// ParseBytes returns a new Parser to read from b, configured by any
+// options given. The Parser reads lines directly from b, without the
+// buffering and copying needed for an io.Reader, so the slices returned
+// by the Line method are part of b; b must not be modified while it is
+// in use. The position of each line in b is given by the Offset method.
+// This is the fastest way to parse a large file, such as a memory dump,
+// that can be read into memory.
+func ParseBytes(b []byte, opts ...Option) *Parser {
+	p := newParser(opts)
+	if p.utf16 {
+		p.setReader(bytes.NewReader(b))
+	} else {
+		p.scanner = &byteScanner{data: b, split: p.split}
+	}
+	return p
+}
+
+// ParseString is like ParseBytes, but reads from a copy of s.
+func ParseString(s string, opts ...Option) *Parser {
+	return ParseBytes([]byte(s), opts...)
+}
+
+func newParser(opts []Option) *Parser {
+	p := &Parser{crAt: -1}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Parser) setReader(r io.Reader) {
+	if p.utf16 {
+		r = newUTF16Reader(r)
+	}
+	scanner := bufio.NewScanner(r)
+	if p.maxLine > 0 {
+		// leave room for a CRLF line terminator
+		scanner.Buffer(nil, p.maxLine+2)
+	}
+	scanner.Split(p.split)
+	p.scanner = scanner
+}
+
+// A lineScanner reads the lines of a Parser's input.
+type lineScanner interface {
+	Scan() bool
+	Bytes() []byte
+	Err() error
 }
 
-// NewParser returns a new Parser to read from r.
-func NewParser(r io.Reader) *Parser {
-	return &Parser{scanner: bufio.NewScanner(r)}
+// A byteScanner is a lineScanner for input that is already in memory.
+type byteScanner struct {
+	data  []byte
+	split bufio.SplitFunc
+	token []byte
+	err   error
+}
+
+func (s *byteScanner) Scan() bool {
+	if s.err != nil || len(s.data) == 0 {
+		return false
+	}
+	advance, token, err := s.split(s.data, true)
+	if err != nil {
+		s.err = err
+		return false
+	}
+	if advance == 0 {
+		return false
+	}
+	s.token = token
+	s.data = s.data[advance:]
+	return true
+}
+
+func (s *byteScanner) Bytes() []byte {
+	return s.token
+}
+
+func (s *byteScanner) Err() error {
+	return s.err
+}
+
+// An Option configures a Parser.
+type Option func(*Parser)
+
+// StopAtEnd makes the Parser stop cleanly at the end record instead of
+// reporting an error for anything that follows it. The unparsed content
+// after the end record can be accessed by the Trailing method.
+func StopAtEnd() Option {
+	return func(p *Parser) {
+		p.stopAtEnd = true
+	}
+}
+
+// A LinePolicy determines how a Parser treats non-empty lines that do
+// not begin with a record mark.
+type LinePolicy int
+
+const (
+	RejectLines LinePolicy = iota // stop with a ParseError (the default)
+	SkipLines                     // ignore the line
+	WarnLines                     // ignore the line, but record a warning
+)
+
+// NonRecordLines sets the policy for lines that do not begin with a
+// record mark, such as banners or terminal artifacts.
+func NonRecordLines(policy LinePolicy) Option {
+	return func(p *Parser) {
+		p.lines = policy
+	}
+}
+
+// Salvage makes the Parser recover what it can from a corrupt data
+// record instead of stopping with an error: the valid prefix of its
+// data is returned as a record for which the Salvaged method reports
+// true, and the error is recorded as a warning. Only damage to the text
+// of a record is salvaged: a record that is too short, has an invalid
+// hex digit or checksum, or is followed by extra characters. Other
+// errors, such as overlapping data, still stop the Parser.
+func Salvage() Option {
+	return func(p *Parser) {
+		p.salvage = true
+	}
+}
+
+// Logger makes the Parser log each warning to l at slog.LevelWarn, with
+// attributes for the line, a short code identifying the kind of
+// warning, and the address of the affected data where there is one.
+// Warnings are still available from the Warnings method.
+func Logger(l *slog.Logger) Option {
+	return func(p *Parser) {
+		p.logger = l
+	}
+}
+
+// FilterRecords makes the Parser drop each record for which pred
+// returns false, so that it has no effect: a dropped data record is not
+// returned by Parse, and a dropped record of another type does not set
+// a base address or start address, or end the file. The Data field of
+// the RawRecord passed to pred is only valid during the call.
+func FilterRecords(pred func(RawRecord) bool) Option {
+	return func(p *Parser) {
+		p.filter = pred
+	}
+}
+
+// AllRecords makes the Parse method return after every record, not
+// just after data records, so that the fields of each record can be
+// accessed by the Raw method. Records of a type unknown to the Parser
+// are returned as they are, without any effect, instead of causing an
+// error. The HasData method reports whether a record was a data record.
+func AllRecords() Option {
+	return func(p *Parser) {
+		p.allRecords = true
+	}
+}
+
+// MaxLineLength sets the length of the longest line, not counting its
+// terminator, that the Parser will read; a longer line stops the Parser
+// with a ParseError. The default limit is a little under 64K, which is
+// far longer than any valid record.
+func MaxLineLength(n int) Option {
+	return func(p *Parser) {
+		p.maxLine = n
+	}
+}
+
+// CollectErrors makes the Parser keep going after an error in a line of
+// input, skipping the line, instead of stopping. The errors can be
+// accessed by the Errors method; once parsing is finished, the Err
+// method returns the first of them. Errors other than a ParseError,
+// such as an error reading the input, still stop the Parser.
+func CollectErrors() Option {
+	return func(p *Parser) {
+		p.collect = true
+	}
+}
+
+// IgnoreChecksums makes the Parser accept records with an invalid
+// checksum.
+func IgnoreChecksums() Option {
+	return func(p *Parser) {
+		p.ignoreSums = true
+	}
+}
+
+// AllowMissingEOF makes the Parser accept input that ends without an
+// end record.
+func AllowMissingEOF() Option {
+	return func(p *Parser) {
+		p.allowNoEnd = true
+	}
+}
+
+// AllowTrailingRecords makes the Parser ignore any lines that follow
+// the end record, including further records, instead of reporting an
+// error.
+func AllowTrailingRecords() Option {
+	return func(p *Parser) {
+		p.allowTrailing = true
+	}
+}
+
+// TrimSpace makes the Parser ignore white space before and after each
+// record.
+func TrimSpace() Option {
+	return func(p *Parser) {
+		p.trimSpace = true
+	}
+}
+
+// Tee makes the Parse method return after every line that it reads,
+// not just after data records, so that the original text of each line
+// can be accessed by the Line method. The HasData method reports
+// whether a line was a data record.
+func Tee() Option {
+	return func(p *Parser) {
+		p.tee = true
+	}
+}
+
+// split is scanLines, except that it remembers the raw bytes
+// (including any line terminator) of each line, and returns everything
+// unsplit once the content after an end record is being collected. A
+// line that starts with a record is decoded as it is split, which finds
+// the end of the line without searching for it.
+func (p *Parser) split(data []byte, atEOF bool) (int, []byte, error) {
+	if p.ended && p.stopAtEnd {
+		p.decoded = 0
+		p.raw = data
+		if len(data) == 0 {
+			return 0, nil, nil
+		}
+		return len(data), data, nil
+	}
+	i := -1
+	switch n := p.decodeLine(data); {
+	case n > 0 && n < len(data) && (data[n] == '\n' || data[n] == '\r'):
+		// a record that decodes holds no line endings, so its line
+		// ends where its length says
+		i = n
+	case n > 0 && n == len(data):
+		// the record ends the line if it ends the input
+	default:
+		p.decoded = 0
+		i = bytes.IndexByte(data[:min(len(data), 1024)], '\n')
+		if i >= 0 {
+			if j := p.indexCR(data, i); j >= 0 {
+				i = j
+			}
+		} else {
+			i = bytes.IndexAny(data, "\r\n")
+		}
+	}
+	advance, token, err := lineAt(data, i, atEOF)
+	p.raw = data[:advance]
+	if advance > 0 {
+		p.offset = p.next
+		p.next += int64(advance)
+	}
+	return advance, token, err
+}
+
+// indexCR returns the index of the first carriage return in data[:n],
+// where data starts at offset p.next in the input, or -1 if there is
+// none. It remembers where it found one, or how far it looked, so that
+// input without carriage returns is searched only once, not line by
+// line.
+func (p *Parser) indexCR(data []byte, n int) int {
+	start, end := p.next, p.next+int64(n)
+	if p.crAt < start {
+		if p.crSearched >= end {
+			return -1
+		}
+		from := max(p.crSearched-start, 0)
+		j := bytes.IndexByte(data[from:], '\r')
+		if j < 0 {
+			p.crSearched = start + int64(len(data))
+			return -1
+		}
+		p.crAt = start + from + int64(j)
+		p.crSearched = p.crAt + 1
+	}
+	if p.crAt < end {
+		return int(p.crAt - start)
+	}
+	return -1
+}
+
+// scanLines is bufio.ScanLines, except that a carriage return on its
+// own also ends a line.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	// IndexByte is much faster than IndexAny, and lines are short, so
+	// look for a newline near the start of data and then for a carriage
+	// return before it
+	i := bytes.IndexByte(data[:min(len(data), 1024)], '\n')
+	if i >= 0 {
+		if j := bytes.IndexByte(data[:i], '\r'); j >= 0 {
+			i = j
+		}
+	} else {
+		i = bytes.IndexAny(data, "\r\n")
+	}
+	return lineAt(data, i, atEOF)
+}
+
+// lineAt returns the line at the start of data, as a split function
+// would, given the index i of the first newline or carriage return in
+// data, or -1.
+func lineAt(data []byte, i int, atEOF bool) (int, []byte, error) {
+	switch {
+	case i < 0:
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	case data[i] == '\n':
+		return i + 1, data[:i], nil
+	case i+1 < len(data):
+		if data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	case atEOF:
+		return i + 1, data[:i], nil
+	}
+	// wait to see whether the carriage return is followed by a newline
+	return 0, nil, nil
 }
 
 // Parse reads the next data record, which can then be accessed by the
@@ -60,60 +493,208 @@ func NewParser(r io.Reader) *Parser {
 // information from record types 3 or 5 can be accessed by the CSIP or
 // EIP methods; an error, if any, can be accessed by the Err method.
 func (p *Parser) Parse() bool {
-NextRec:
-	if p.err != nil {
-		return false
+	if p.start.IsZero() {
+		p.start = time.Now()
 	}
-	if p.wrap != nil {
-		p.data = *p.wrap
-		p.wrap = nil
-		return true
+	ok := p.parse()
+	if !ok && p.elapsed == 0 {
+		p.elapsed = time.Since(p.start)
 	}
-	if !p.scanLine() {
-		return false
+	return ok
+}
+
+func (p *Parser) parse() bool {
+	for {
+		if p.parseNext() {
+			return true
+		}
+		if !p.collect || p.collected || !p.collectError() {
+			return false
+		}
+	}
+}
+
+// parseNext reads lines until it reads a data record, or a line that
+// Parse should return, returning false if parsing stops instead.
+func (p *Parser) parseNext() bool {
+	for p.err == nil {
+		if len(p.queue) > 0 {
+			p.data = p.queue[0]
+			p.queue = p.queue[1:]
+			p.text = nil
+			p.hasData = true
+			return true
+		}
+		if p.wrap != nil {
+			p.data = *p.wrap
+			p.wrap = nil
+			p.text = nil
+			if p.transformData() {
+				return true
+			}
+			continue
+		}
+		p.salvaged = false
+		if !p.scanLine() {
+			return false
+		}
+		p.text = p.raw
+		p.isRecord = false
+		p.hasData = p.parseLine(p.scanner.Bytes())
+		if p.hasData {
+			p.hasData = p.transformData()
+		}
+		if p.err == nil && (p.hasData || p.tee ||
+			(p.allRecords && p.isRecord)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLine parses a line of input, returning true if it was a data
+// record.
+func (p *Parser) parseLine(b []byte) bool {
+	if p.line == 1 {
+		if b = p.checkEncoding(b); p.err != nil {
+			return false
+		}
+	}
+	if p.trimSpace {
+		b = bytes.TrimSpace(b)
 	}
-	b := p.scanner.Bytes()
 	if len(b) == 0 {
-		goto NextRec
+		return false
+	}
+	if !p.gotRecord && isBinary(b) {
+		p.err = ErrBinaryInput
+		return false
+	}
+	if p.atTrailer {
+		return false
 	}
 	if b[0] != ':' {
-		p.err = p.makeError("missing record mark")
+		p.skipLine()
 		return false
 	}
-	p.b = b[1:]
-	p.sum = 0
-	reclen := p.readByteField()
-	offset := p.readWordField()
-	rectyp := p.readByteField()
-	p.checkRecLen(rectyp, reclen)
-	if !p.parseInfo(rectyp, reclen, offset) {
-		goto NextRec
+	p.gotRecord = true
+	p.rec = b
+	p.corrupt = false
+	var gotData bool
+	rectyp, reclen, offset, data, ok := p.decodeRecord(b)
+	if ok {
+		p.b = b[len(b):]
+		p.checkRecLen(rectyp, reclen)
+		p.checkFormat(rectyp, reclen, offset)
+		p.dropped = false
+		gotData = p.err != nil || p.useRecord(rectyp, offset, data)
+	} else {
+		// read field by field, to report any error exactly
+		p.b = b[1:]
+		p.sum = 0
+		reclen = p.readByteField()
+		offset = p.readWordField()
+		rectyp = p.readByteField()
+		headerOK := p.err == nil
+		p.checkRecLen(rectyp, reclen)
+		p.checkFormat(rectyp, reclen, offset)
+		p.checkAvail(reclen)
+		gotData = p.parseInfo(rectyp, reclen, offset)
+		if p.err != nil && headerOK && p.corrupt && rectyp == 0 && p.salvage {
+			gotData = p.salvageData(reclen, offset)
+		}
 	}
-	return p.err == nil
+	if p.err == nil {
+		p.nrec++
+		p.isRecord = !p.dropped
+		if p.onRecord != nil && !p.dropped {
+			p.onRecord(rectyp, reclen)
+		}
+	}
+	return gotData
 }
 
 func (p *Parser) scanLine() bool {
-	if ok := p.scanner.Scan(); !ok {
-		p.err = p.scanner.Err()
+	if p.ended && p.stopAtEnd {
+		p.eof = true
+		p.readTrailing()
 		if p.err == nil {
-			if !p.ended {
-				p.err = p.makeError("missing end record")
-			}
+			p.checkTrailer()
 		}
 		return false
 	}
-	if p.ended {
-		p.err = p.makeError("record after end")
-		return false
+	for p.scanner.Scan() {
+		if p.maxLine > 0 && len(p.scanner.Bytes()) > p.maxLine {
+			p.err = ParseError{Line: p.line + 1, Msg: "line too long"}
+			return false
+		}
+		if p.trailer != nil {
+			p.atTrailer = p.readTrailer(p.scanner.Bytes(), p.line+1)
+			if p.err != nil {
+				return false
+			}
+		}
+		if p.ended && !p.atTrailer {
+			if p.allowTrailing {
+				p.line++
+				continue
+			}
+			p.err = p.makeError("record after end")
+			p.line++
+			return false
+		}
+		p.line++
+		return true
+	}
+	p.eof = true
+	p.err = p.scanner.Err()
+	if errors.Is(p.err, bufio.ErrTooLong) {
+		p.err = ParseError{Line: p.line + 1, Msg: "line too long"}
+	}
+	if p.err == nil {
+		if !p.ended && !p.allowNoEnd {
+			p.err = p.makeError("missing end record")
+		} else {
+			p.checkTrailer()
+		}
+	}
+	return false
+}
+
+func (p *Parser) readTrailing() {
+	for p.scanner.Scan() {
+		p.trailing = append(p.trailing, p.raw...)
+	}
+	p.err = p.scanner.Err()
+}
+
+// isBinary reports whether a line that precedes the first record looks
+// like binary data.
+func isBinary(b []byte) bool {
+	return bytes.IndexByte(b, 0) >= 0 || !utf8.Valid(b)
+}
+
+// skipLine applies the LinePolicy to a line without a record mark.
+func (p *Parser) skipLine() {
+	switch p.lines {
+	case SkipLines:
+	case WarnLines:
+		p.warn("non-record-line", "missing record mark")
+	default:
+		p.err = p.makeError("missing record mark")
 	}
-	p.line++
-	return true
 }
 
 func (p *Parser) checkRecLen(rectyp, reclen byte) {
 	if p.err != nil {
 		return
 	}
+	if int(rectyp) >= len(reclens) {
+		if !p.allRecords {
+			p.err = p.makeError("invalid record type")
+		}
+		return
+	}
 	if rectyp > 0 && reclen != reclens[rectyp] {
 		p.err = p.makeError("invalid record length")
 		return
@@ -122,17 +703,100 @@ func (p *Parser) checkRecLen(rectyp, reclen byte) {
 
 var reclens = [...]byte{0, 0, 2, 4, 2, 4}
 
+// checkAvail makes sure the rest of the record holds the reclen data
+// bytes and the checksum.
+func (p *Parser) checkAvail(reclen byte) {
+	if p.err != nil {
+		return
+	}
+	want := int(reclen) + 1
+	if have := len(p.b) / 2; have < want {
+		p.corrupt = true
+		p.err = p.makeError(fmt.Sprintf(
+			"record too short: expected %d bytes, found %d (column %d)",
+			want, have, p.column()+2*have))
+	}
+}
+
+// column returns the 1-based column of the next unread character of
+// the current record.
+func (p *Parser) column() int {
+	return len(p.rec) - len(p.b) + 1
+}
+
 // Data returns the last record read by the Parse method. The
 // underlying data may be overwritten by subsequent calls to Parse.
 func (p *Parser) Data() Record {
 	return p.data
 }
 
+// Raw returns the fields of the last record read by the Parse method,
+// if the Parser was created with the AllRecords option. The underlying
+// data may be overwritten by subsequent calls to Parse.
+func (p *Parser) Raw() RawRecord {
+	return p.rawRec
+}
+
+// LineNumber returns the number of the line read by the last call to
+// Parse, counting from 1.
+func (p *Parser) LineNumber() int {
+	return p.line
+}
+
 // Err returns the first error that was encountered by the Parser.
 func (p *Parser) Err() error {
 	return p.err
 }
 
+// HasData reports whether the last call to Parse read a data record,
+// which can then be accessed by the Data method. It is always true after
+// Parse returns true, unless the Parser was created with the Tee or
+// AllRecords option.
+func (p *Parser) HasData() bool {
+	return p.hasData
+}
+
+// Offset returns the byte offset in the input of the start of the line
+// read by the last call to Parse. For UTF-16 input, it is the offset in
+// the input as converted to UTF-8.
+func (p *Parser) Offset() int64 {
+	return p.offset
+}
+
+// Line returns the untouched text of the line read by the last call to
+// Parse, including any line terminator. It returns nil when Parse
+// returned the second part of a data record that was split at a segment
+// boundary, which shares the line of the first part. The underlying
+// data may be overwritten by subsequent calls to Parse.
+func (p *Parser) Line() []byte {
+	return p.text
+}
+
+// Salvaged reports whether the record returned by the Data method was
+// recovered from a corrupt data record.
+func (p *Parser) Salvaged() bool {
+	return p.salvaged
+}
+
+// Errors returns the errors in the input that the Parser skipped, if it
+// was created with the CollectErrors option, in the order they were
+// encountered.
+func (p *Parser) Errors() []ParseError {
+	return p.errors
+}
+
+// Warnings returns the problems that the Parser tolerated instead of
+// stopping with an error, in the order they were encountered.
+func (p *Parser) Warnings() []ParseError {
+	return p.warnings
+}
+
+// Trailing returns the content that followed the end record, if the
+// Parser was created with the StopAtEnd option.
+func (p *Parser) Trailing() []byte {
+	return p.trailing
+}
+
 // CSIP returns cs and ip with ok true if the parser read a record of
 // type 3; otherwise it returns with ok false.
 func (p *Parser) CSIP() (cs uint16, ip uint16, ok bool) {
@@ -146,69 +810,163 @@ func (p *Parser) EIP() (eip uint32, ok bool) {
 }
 
 func (p *Parser) parseInfo(rectyp, reclen byte, offset uint16) bool {
+	p.dropped = false
 	if p.err != nil {
 		return true
 	}
+	data := p.readField(reclen)
+	p.endRecord(rectyp, offset, reclen)
+	if p.err != nil {
+		return true
+	}
+	return p.useRecord(rectyp, offset, data)
+}
+
+// useRecord acts on a record whose fields have all been read, returning
+// true if it was a data record.
+func (p *Parser) useRecord(rectyp byte, offset uint16, data []byte) bool {
+	if p.filter != nil || p.allRecords {
+		p.rawRec = RawRecord{
+			Type:     rectyp,
+			Offset:   offset,
+			Data:     data,
+			Checksum: p.field[255],
+		}
+		if rectyp == 0 {
+			p.rawRec.Address = p.address(offset)
+		}
+		if p.filter != nil {
+			if p.dropped = !p.filter(p.rawRec); p.dropped {
+				return false
+			}
+		}
+	}
 	gotData := false
 	switch rectyp {
 	case 0:
-		p.data.Bytes = p.readField(reclen)
-		if p.useSBA {
-			p.data.Address = p.sba + uint32(offset)
-		} else if p.useLBA {
-			p.data.Address = p.lba | uint32(offset)
-		} else {
-			p.data.Address = uint32(offset)
-		}
-		if !p.useLBA {
-			next := int(offset) + len(p.data.Bytes)
-			extra := (next - 1) - 0xffff
-			if extra > 0 {
-				p.wrap = &Record{}
-				// p.sba == 0 if useSBA is false
-				p.wrap.Address = p.sba + (uint32(next-1) & 0xffff)
-				p.wrap.Bytes = p.data.Bytes[extra:]
-				p.data.Bytes = p.data.Bytes[:extra]
-			}
+		p.decodeWords(offset, data)
+		if p.err != nil {
+			return false
 		}
+		p.setData(offset, data)
 		gotData = true
 	case 1:
 		p.ended = true
 	case 2:
-		p.sba = uint32(p.readWordField()) << 4
+		p.sba = uint32(word(data)) << 4
 		p.useSBA = true
 		p.lba = 0
 		p.useLBA = false
 	case 3:
-		p.cs = p.readWordField()
-		p.ip = p.readWordField()
+		p.cs = word(data)
+		p.ip = word(data[2:])
 		p.hasCSIP = true
 	case 4:
 		p.sba = 0
 		p.useSBA = false
-		p.lba = uint32(p.readWordField()) << 16
+		p.lba = uint32(word(data)) << 16
 		p.useLBA = true
 	case 5:
-		p.eip = uint32(p.readWordField())
+		p.eip = uint32(word(data))
 		p.eip <<= 16
-		p.eip |= uint32(p.readWordField())
+		p.eip |= uint32(word(data[2:]))
 		p.hasEIP = true
 	}
-	p.endRecord()
 	return gotData
 }
 
-func (p *Parser) endRecord() {
+func word(b []byte) uint16 {
+	return (uint16(b[0]) << 8) | uint16(b[1])
+}
+
+// address returns the address of a data record with the given load
+// offset, calculated from the segment that is in effect.
+func (p *Parser) address(offset uint16) uint32 {
+	if p.useSBA {
+		return p.sba + uint32(offset)
+	} else if p.useLBA {
+		return p.lba | uint32(offset)
+	}
+	return uint32(offset)
+}
+
+// setData sets the current data record from its load offset and bytes,
+// splitting it if it wraps around a segment. It returns the address of
+// the first byte.
+func (p *Parser) setData(offset uint16, bytes []byte) uint32 {
+	p.nbytes += len(bytes)
+	if p.hash != nil {
+		p.hash.Write(bytes)
+	}
+	p.data.Bytes = bytes
+	p.data.Address = p.address(offset)
+	if !p.useLBA {
+		next := int(offset) + len(p.data.Bytes)
+		extra := (next - 1) - 0xffff
+		if extra > 0 {
+			p.wrap = &Record{}
+			// p.sba == 0 if useSBA is false
+			p.wrap.Address = p.sba + (uint32(next-1) & 0xffff)
+			p.wrap.Bytes = p.data.Bytes[extra:]
+			p.data.Bytes = p.data.Bytes[:extra]
+		}
+	}
+	if p.overlaps != nil {
+		p.checkOverlap(p.data)
+		if p.wrap != nil {
+			p.checkOverlap(*p.wrap)
+		}
+	}
+	return p.data.Address
+}
+
+// salvageData replaces the error from a corrupt data record with a
+// warning, and sets the current data record to the valid prefix of its
+// data. It returns false if no data could be recovered.
+func (p *Parser) salvageData(reclen byte, offset uint16) bool {
+	msg := p.err.Error()
+	var perr ParseError
+	if errors.As(p.err, &perr) {
+		msg = perr.Msg
+	}
+	p.err = nil
+	text := p.rec[9:]
+	n := min(len(text)/2, int(reclen))
+	nd, _ := hex.Decode(p.field[:], text[:2*n])
+	if nd == 0 {
+		p.warn("salvaged-record", "salvaged data record: "+msg)
+		return false
+	}
+	addr := p.setData(offset, p.field[:nd])
+	p.rawRec = RawRecord{Offset: offset, Address: addr, Data: p.field[:nd]}
+	p.warn("salvaged-record", "salvaged data record: "+msg,
+		slog.Uint64("address", uint64(addr)))
+	p.salvaged = true
+	return true
+}
+
+func (p *Parser) endRecord(rectyp byte, offset uint16, reclen byte) {
 	// read checksum without overwriting the previous field
 	p.readFieldInto(1, p.field[255:])
 	if p.err != nil {
 		return
 	}
-	if p.sum != 0 {
-		p.err = p.makeError("invalid checksum")
+	if p.sum != 0 && !p.ignoreSums {
+		stored := p.field[255]
+		computed := stored - p.sum
+		msg := fmt.Sprintf("invalid checksum: stored %02X, computed %02X",
+			stored, computed)
+		if rectyp == 0 && reclen > 0 {
+			start := p.address(offset)
+			msg += fmt.Sprintf(" (address %08X-%08X)",
+				start, start+uint32(reclen)-1)
+		}
+		p.corrupt = true
+		p.err = p.makeError(msg)
 		return
 	}
 	if len(p.b) > 0 {
+		p.corrupt = true
 		p.err = p.makeError("trailing data")
 	}
 }
@@ -237,22 +995,165 @@ func (p *Parser) readFieldInto(n byte, field []byte) []byte {
 	if p.err != nil {
 		return nil
 	}
-	var nd int
-	nd, p.err = hex.Decode(field[:], p.b[:n*2])
-	p.b = p.b[nd*2:]
-	if byte(nd) < n {
-		p.err = p.makeError("record too short")
+	if have := len(p.b) / 2; have < int(n) {
+		p.corrupt = true
+		p.err = p.makeError(fmt.Sprintf("record too short (column %d)",
+			p.column()+2*have))
 		return nil
 	}
-	if p.err != nil {
+	dst := field[:n]
+	src := p.b[:2*len(dst)]
+	var sum, bad byte
+	for i := range dst {
+		hi, lo := hexValues[src[2*i]], hexValues[src[2*i+1]]
+		bad |= hi | lo
+		dst[i] = hi<<4 | lo
+		sum += dst[i]
+	}
+	if bad > 0xf {
+		// let hex.Decode report the invalid byte
+		p.corrupt = true
+		var nd int
+		nd, p.err = hex.Decode(field[:], src)
+		p.b = p.b[nd*2:]
 		return nil
 	}
-	for i := 0; i < nd; i++ {
-		p.sum += field[i]
+	p.sum += sum
+	p.b = p.b[len(src):]
+	return dst
+}
+
+// hexValues maps each hexadecimal digit to its value, and any other
+// byte to 0xff.
+var hexValues = func() (t [256]byte) {
+	for i := range t {
+		t[i] = 0xff
+	}
+	for i, c := range []byte("0123456789abcdef") {
+		t[c] = byte(i)
 	}
-	return field[:nd]
+	for i, c := range []byte("ABCDEF") {
+		t[c] = byte(10 + i)
+	}
+	return t
+}()
+
+// decodeRecord returns the fields of the record in line, decoding it
+// into p.record unless split already has. It returns ok false, leaving
+// the record to be read field by field, unless the text of the record is
+// all hexadecimal digits, its length agrees with the record length
+// field, and its checksum is correct or ignored.
+func (p *Parser) decodeRecord(line []byte) (rectyp, reclen byte, offset uint16, data []byte, ok bool) {
+	if p.decoded != len(line) && p.decodeLine(line) != len(line) {
+		return
+	}
+	p.decoded = 0
+	if p.decodedSum != 0 && !p.ignoreSums {
+		return
+	}
+	rec := p.record[:len(line)/2]
+	p.sum = p.decodedSum
+	p.field[255] = rec[len(rec)-1]
+	offset = uint16(rec[1])<<8 | uint16(rec[2])
+	return rec[3], rec[0], offset, rec[4 : len(rec)-1], true
 }
 
+// decodeLine decodes the record at the start of data into p.record,
+// returning the length of its text, or 0 if data does not start with a
+// whole record that is all hexadecimal digits after the record mark.
+func (p *Parser) decodeLine(data []byte) int {
+	p.decoded = 0
+	if len(data) < 11 || data[0] != ':' {
+		return 0
+	}
+	hi, lo := hexValues[data[1]], hexValues[data[2]]
+	n := 11 + 2*int(hi<<4|lo)
+	if hi|lo > 0xf || len(data) < n {
+		return 0
+	}
+	sum, valid := decodeHex(p.record[:n/2], data[1:n])
+	if !valid {
+		return 0
+	}
+	p.decoded, p.decodedSum = n, sum
+	return n
+}
+
+// decodeHex decodes the hexadecimal digits in src into dst, which must
+// be half as long, returning the sum of the decoded bytes, and valid
+// false if src holds anything but hexadecimal digits.
+func decodeHex(dst, src []byte) (sum byte, valid bool) {
+	var bad, total uint16
+	for len(dst) >= 4 && len(src) >= 8 {
+		// look up four pairs at a time
+		v := binary.LittleEndian.Uint64(src)
+		x := hexPairs[uint16(v)]
+		dst[0], bad, total = byte(x), bad|x, total+x
+		x = hexPairs[uint16(v>>16)]
+		dst[1], bad, total = byte(x), bad|x, total+x
+		x = hexPairs[uint16(v>>32)]
+		dst[2], bad, total = byte(x), bad|x, total+x
+		x = hexPairs[v>>48]
+		dst[3], bad, total = byte(x), bad|x, total+x
+		dst, src = dst[4:], src[8:]
+	}
+	for i := range dst {
+		x := hexPairs[binary.LittleEndian.Uint16(src[2*i:])]
+		dst[i], bad, total = byte(x), bad|x, total+x
+	}
+	return byte(total), bad < 0x100
+}
+
+// hexPairs maps each pair of bytes, read as a little-endian uint16, to
+// the value of the pair as hexadecimal digits, or to 0x100 if either
+// is not a hexadecimal digit.
+var hexPairs = func() (t [1 << 16]uint16) {
+	for i := range t {
+		hi, lo := hexValues[byte(i)], hexValues[byte(i>>8)]
+		t[i] = uint16(hi)<<4 | uint16(lo)
+		if hi|lo > 0xf {
+			t[i] = 0x100
+		}
+	}
+	return t
+}()
+
 func (p *Parser) makeError(msg string) error {
 	return ParseError{Line: p.line, Msg: msg}
 }
+
+// collectError records the current error, if it is a ParseError, and
+// clears it so that parsing can continue, returning false if parsing
+// cannot continue. At the end of the input, the first error recorded
+// becomes the current error.
+func (p *Parser) collectError() bool {
+	var perr ParseError
+	if p.err != nil {
+		if !errors.As(p.err, &perr) {
+			return false
+		}
+		p.errors = append(p.errors, perr)
+		p.err = nil
+		p.wrap = nil
+		p.queue = nil
+	}
+	if p.eof {
+		p.collected = true
+		if len(p.errors) > 0 {
+			p.err = p.errors[0]
+		}
+		return false
+	}
+	return true
+}
+
+// warn records a warning, and logs it with the given code and any extra
+// attributes if the Parser has a Logger.
+func (p *Parser) warn(code, msg string, attrs ...slog.Attr) {
+	p.warnings = append(p.warnings, ParseError{Line: p.line, Msg: msg})
+	if p.logger == nil {
+		return
+	}
+	attrs = append(attrs, slog.Int("line", p.line), slog.String("code", code))
+	p.logger.LogAttrs(context.Background(), slog.LevelWarn, msg, attrs...)
+}
@@ -1,7 +1,12 @@
 package ihex
 
 import (
+	"bytes"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"strings"
 	"testing"
 )
@@ -84,6 +89,7 @@ func TestBad(t *testing.T) {
 		{"00000001FF", "missing record mark"},
 		{":01000001FF", "invalid record length"},
 		{":00000001FE", "invalid checksum"},
+		{":00000006FA", "invalid record type"},
 	}
 	for _, data := range cases {
 		p := NewParser(strings.NewReader(data[0]))
@@ -143,3 +149,803 @@ func TestSegWrap(t *testing.T) {
 		t.Error("incorrect post-wrap data")
 	}
 }
+
+func TestChecksumError(t *testing.T) {
+	var cases = [][]string{
+		{":00000001FE", "line 1: invalid checksum: stored FE, computed FF"},
+		{":0B0010006164647265737320676170A6",
+			"line 1: invalid checksum: stored A6, computed A7 " +
+				"(address 00000010-0000001A)"},
+	}
+	for _, data := range cases {
+		p := NewParser(strings.NewReader(data[0]))
+		p.Parse()
+		if p.Err() == nil || p.Err().Error() != data[1] {
+			t.Errorf("expected %q, got %v", data[1], p.Err())
+		}
+	}
+}
+
+func TestStopAtEnd(t *testing.T) {
+	record := ":0B0010006164647265737320676170A7\n:00000001FF\r\n" +
+		"SIGNATURE\x00\xff\n:020000021200EA"
+	p := NewParser(strings.NewReader(record), StopAtEnd())
+	n := 0
+	for p.Parse() {
+		n++
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	if n != 1 {
+		t.Error("expected 1 record, got", n)
+	}
+	if string(p.Trailing()) != "SIGNATURE\x00\xff\n:020000021200EA" {
+		t.Errorf("incorrect trailing content %q", p.Trailing())
+	}
+
+	p = NewParser(strings.NewReader(":00000001FF"), StopAtEnd())
+	for p.Parse() {
+	}
+	if p.Err() != nil || len(p.Trailing()) != 0 {
+		t.Error("unexpected trailing content or error")
+	}
+}
+
+func TestNonRecordLines(t *testing.T) {
+	records := `Firmware v1.2
+:0B0010006164647265737320676170A7
+C:\>
+:00000001FF
+`
+	p := NewParser(strings.NewReader(records))
+	for p.Parse() {
+	}
+	if p.Err() == nil || p.Err().Error() != "line 1: missing record mark" {
+		t.Error("missed missing record mark")
+	}
+
+	p = NewParser(strings.NewReader(records), NonRecordLines(SkipLines))
+	n := 0
+	for p.Parse() {
+		n++
+	}
+	if p.Err() != nil || n != 1 {
+		t.Error("unexpected error or record count", p.Err(), n)
+	}
+	if len(p.Warnings()) != 0 {
+		t.Error("unexpected warnings")
+	}
+
+	p = NewParser(strings.NewReader(records), NonRecordLines(WarnLines))
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	warnings := p.Warnings()
+	if len(warnings) != 2 || warnings[0].Line != 1 || warnings[1].Line != 3 {
+		t.Error("incorrect warnings", warnings)
+	}
+}
+
+func toUTF16(s string, bigEndian bool, bom bool) string {
+	var b []byte
+	if bom {
+		s = "\ufeff" + s
+	}
+	for _, r := range s {
+		if bigEndian {
+			b = append(b, byte(r>>8), byte(r))
+		} else {
+			b = append(b, byte(r), byte(r>>8))
+		}
+	}
+	return string(b)
+}
+
+func TestEncoding(t *testing.T) {
+	records := ":0B0010006164647265737320676170A7\r\n:00000001FF\r\n"
+
+	p := NewParser(strings.NewReader("\ufeff" + records))
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Error("unexpected error with UTF-8 BOM", p.Err())
+	}
+
+	inputs := []string{
+		toUTF16(records, false, true),
+		toUTF16(records, true, true),
+		toUTF16(records, false, false),
+		toUTF16(records, true, false),
+	}
+	for _, input := range inputs {
+		p = NewParser(strings.NewReader(input))
+		p.Parse()
+		if p.Err() == nil ||
+			p.Err().Error() != "line 1: file is UTF-16 encoded" {
+			t.Error("missed UTF-16 input", p.Err())
+		}
+
+		p = NewParser(strings.NewReader(input), TranscodeUTF16())
+		n := 0
+		for p.Parse() {
+			if p.Data().Address != 0x10 {
+				t.Error("wrong address")
+			}
+			n++
+		}
+		if p.Err() != nil || n != 1 {
+			t.Error("unexpected error or record count", p.Err(), n)
+		}
+	}
+
+	p = NewParser(strings.NewReader(records), TranscodeUTF16())
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Error("unexpected error with transcoding", p.Err())
+	}
+}
+
+func TestBinaryInput(t *testing.T) {
+	inputs := []string{
+		"\x0c\x94\x5c\x00\x0c\x94\x6e\x00\n:00000001FF",
+		// a Cortex-M vector table, whose stack pointer looks like UTF-16
+		"\x00\x50\x00\x20\xc1\x01\x00\x08\xc9\x01\x00\x08",
+		"\x7fELF\x02\x01\x01\x00",
+		"\x00\x00\x00\x00",
+	}
+	for _, input := range inputs {
+		for _, opts := range [][]Option{nil, {TranscodeUTF16()}} {
+			opts = append(opts, NonRecordLines(SkipLines))
+			p := NewParser(strings.NewReader(input), opts...)
+			p.Parse()
+			if p.Err() != ErrBinaryInput {
+				t.Errorf("missed binary input %q: %v", input, p.Err())
+			}
+		}
+	}
+
+	records := "Built by équipe\n:00000001FF\n"
+	p := NewParser(strings.NewReader(records), NonRecordLines(SkipLines))
+	p.Parse()
+	if p.Err() != nil {
+		t.Error("unexpected error", p.Err())
+	}
+}
+
+func TestShortRecord(t *testing.T) {
+	var cases = [][]string{
+		{":0C0010006164647265737320676170A7",
+			"line 1: record too short: expected 13 bytes, found 12 (column 34)"},
+		{":FF0010006164647265737320676170A",
+			"line 1: record too short: expected 256 bytes, found 11 (column 32)"},
+		{":000000", "line 1: record too short (column 8)"},
+		{":00000001",
+			"line 1: record too short: expected 1 bytes, found 0 (column 10)"},
+	}
+	for _, data := range cases {
+		p := NewParser(strings.NewReader(data[0]))
+		p.Parse()
+		if p.Err() == nil || p.Err().Error() != data[1] {
+			t.Errorf("expected %q, got %v", data[1], p.Err())
+		}
+	}
+}
+
+func TestSalvage(t *testing.T) {
+	records := `
+:0B0010006164647265737320676170A6
+:0B0020006164647265737X20676170A7
+:0B003000616464726573
+:0B004000616464726573732067617077
+:00000001FF
+`
+	lens := []int{11, 6, 6, 11}
+	salvaged := []bool{true, true, true, false}
+	p := NewParser(strings.NewReader(records), Salvage())
+	n := 0
+	for p.Parse() {
+		data := p.Data()
+		if data.Address != uint32(0x10*(n+1)) || len(data.Bytes) != lens[n] {
+			t.Error("incorrect record", n, data.Address, len(data.Bytes))
+		}
+		if p.Salvaged() != salvaged[n] {
+			t.Error("incorrect salvage flag for record", n)
+		}
+		n++
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	if n != 4 || len(p.Warnings()) != 3 {
+		t.Error("incorrect record or warning count", n, len(p.Warnings()))
+	}
+
+	p = NewParser(strings.NewReader(":0B0010006164647265737320676170A6"))
+	p.Parse()
+	if p.Err() == nil {
+		t.Error("salvaged without option")
+	}
+
+	overlap := ":0100000041BE\n:0100000042BD\n:00000001FF\n"
+	p = NewParser(strings.NewReader(overlap), Salvage(), DetectOverlaps())
+	n = 0
+	for p.Parse() {
+		n++
+	}
+	if p.Err() == nil || n != 1 || len(p.Warnings()) != 0 {
+		t.Error("salvaged overlapping record", n, p.Err(), p.Warnings())
+	}
+	if m := p.Metrics(); m.Bytes != 2 {
+		t.Error("incorrect byte count", m.Bytes)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	records := `banner
+:0B0010006164647265737320676170A6
+:00000001FF
+`
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	p := NewParser(strings.NewReader(records),
+		NonRecordLines(WarnLines), Salvage(), Logger(slog.New(h)))
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	expected := `level=WARN msg="missing record mark" line=1 code=non-record-line
+level=WARN msg="salvaged data record: invalid checksum: stored A6, computed A7 (address 00000010-0000001A)" address=16 line=2 code=salvaged-record
+`
+	if buf.String() != expected {
+		t.Errorf("incorrect log output:\n%s", buf.String())
+	}
+}
+
+func TestTee(t *testing.T) {
+	records := "banner\r\n\n" +
+		":020000021200EA\r\n" +
+		":02FFFF00000000\n" +
+		":0B0010006164647265737320676170A7\n" +
+		":00000001FF"
+	lines := []string{
+		"banner\r\n", "\n", ":020000021200EA\r\n", ":02FFFF00000000\n", "",
+		":0B0010006164647265737320676170A7\n", ":00000001FF",
+	}
+	hasData := []bool{false, false, false, true, true, true, false}
+	p := NewParser(strings.NewReader(records),
+		Tee(), NonRecordLines(SkipLines))
+	var out []byte
+	n := 0
+	for p.Parse() {
+		if string(p.Line()) != lines[n] || p.HasData() != hasData[n] {
+			t.Errorf("line %d: got %q, %v", n, p.Line(), p.HasData())
+		}
+		out = append(out, p.Line()...)
+		n++
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	if n != len(lines) || string(out) != records {
+		t.Errorf("incorrect output %q", out)
+	}
+}
+
+func TestParseRecords(t *testing.T) {
+	records := []byte(`
+:0B0010006164647265737320676170A7
+:00000001FF
+`)
+	recs := MustParseRecords(records)
+	if len(recs) != 1 || recs[0].Address != 0x10 ||
+		string(recs[0].Bytes) != "address gap" {
+		t.Error("incorrect records", recs)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("missed panic")
+		}
+	}()
+	MustParseRecords([]byte(":00000001FE"))
+}
+
+func TestRecordsIter(t *testing.T) {
+	records := `:0100100001EE
+:0100110004EA
+:00000001FE
+`
+	var got []Record
+	var errs []error
+	for r, err := range ParseString(records).Records() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		got = append(got, Record{r.Address, bytes.Clone(r.Bytes)})
+	}
+	checkRecords(t, got, []Record{{0x10, []byte{1}}, {0x11, []byte{4}}})
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(),
+		"line 3: invalid checksum") {
+		t.Errorf("expected checksum error, got %v", errs)
+	}
+
+	n := 0
+	for range ParseString(records).Records() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("expected iteration to stop after 1 record, got %d", n)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	records := `:0100100001EE
+:0100110004EA
+:020012000506E1
+:0100200007D8
+:00000001FF
+`
+	blocks := func(input string, maxLen int) ([]Record, error) {
+		var got []Record
+		for r, err := range ParseString(input).Blocks(maxLen) {
+			if err != nil {
+				return got, err
+			}
+			got = append(got, Record{r.Address, bytes.Clone(r.Bytes)})
+		}
+		return got, nil
+	}
+	got, err := blocks(records, 0)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, got, []Record{
+		{0x10, []byte{1, 4, 5, 6}},
+		{0x20, []byte{7}},
+	})
+	got, _ = blocks(records, 3)
+	checkRecords(t, got, []Record{
+		{0x10, []byte{1, 4, 5}},
+		{0x13, []byte{6}},
+		{0x20, []byte{7}},
+	})
+	got, err = blocks(records[:28]+":00000001FE\n", 0)
+	if err == nil {
+		t.Error("expected error")
+	}
+	checkRecords(t, got, []Record{{0x10, []byte{1, 4}}})
+}
+
+func TestParseBytes(t *testing.T) {
+	records := `
+:020000021200EA
+:02FFFF00000000
+:0B0010006164647265737320676170A7
+:00000001FF
+junk`
+	addrs := []uint32{0x21fff, 0x12000, 0x12010}
+	parsers := []*Parser{
+		ParseString(records),
+		ParseBytes([]byte(records)),
+		ParseBytes([]byte(toUTF16(records, false, true)), TranscodeUTF16()),
+	}
+	for _, p := range parsers {
+		n := 0
+		for p.Parse() {
+			if p.Data().Address != addrs[n] {
+				t.Error("Expected", addrs[n], "but got", p.Data().Address)
+			}
+			n++
+		}
+		if p.Err() == nil || p.Err().Error() != "line 5: record after end" {
+			t.Error("missed record after end", p.Err())
+		}
+	}
+
+	p := ParseString(records, StopAtEnd())
+	for p.Parse() {
+	}
+	if p.Err() != nil || string(p.Trailing()) != "junk" {
+		t.Errorf("incorrect trailing content %q", p.Trailing())
+	}
+
+	p = ParseString("")
+	p.Parse()
+	if p.Err() == nil || p.Err().Error() != "line 0: missing end record" {
+		t.Error("missed missing end record")
+	}
+}
+
+func TestFilterRecords(t *testing.T) {
+	records := `
+:0B0010006164647265737320676170A7
+:020000021200EA
+:0B0010006164647265737320676170A7
+:0400000300001234B3
+:00000001FF
+`
+	var types []byte
+	keep := func(r RawRecord) bool {
+		types = append(types, r.Type)
+		return r.Type != 2 && r.Type != 3 && r.Address < 0x100
+	}
+	p := NewParser(strings.NewReader(records), FilterRecords(keep))
+	var addrs []uint32
+	for p.Parse() {
+		addrs = append(addrs, p.Data().Address)
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	if len(addrs) != 2 || addrs[0] != 0x10 || addrs[1] != 0x10 {
+		t.Error("incorrect records", addrs)
+	}
+	if string(types) != "\x00\x02\x00\x03\x01" {
+		t.Errorf("incorrect types %q", types)
+	}
+	if _, _, ok := p.CSIP(); ok {
+		t.Error("unexpected CS:IP")
+	}
+
+	p = NewParser(strings.NewReader(records),
+		FilterRecords(func(r RawRecord) bool { return r.Type != 1 }))
+	for p.Parse() {
+	}
+	if p.Err() == nil || p.Err().Error() != "line 6: missing end record" {
+		t.Error("missed missing end record", p.Err())
+	}
+}
+
+func TestLenient(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  []Option
+	}{
+		{":0100000001FF\n:00000001FF\n", []Option{IgnoreChecksums()}},
+		{":0100000001FE\n", []Option{AllowMissingEOF()}},
+		{":0100000001FE\n:00000001FF\n\n:0100000001FE\n",
+			[]Option{AllowTrailingRecords()}},
+		{"  :0100000001FE \t\n:00000001FF\n", []Option{TrimSpace()}},
+		// lowercase hex and CR line endings are always accepted
+		{":0100000001fe\r:00000001FF\r", nil},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input))
+		for p.Parse() {
+		}
+		if p.Err() == nil && tt.opts != nil {
+			t.Errorf("%q: expected error without options", tt.input)
+		}
+		p = NewParser(strings.NewReader(tt.input), tt.opts...)
+		n := 0
+		for p.Parse() {
+			n++
+		}
+		if p.Err() != nil {
+			t.Errorf("%q: unexpected error %v", tt.input, p.Err())
+		}
+		if n != 1 {
+			t.Errorf("%q: expected 1 record, got %d", tt.input, n)
+		}
+	}
+}
+
+func TestAllRecords(t *testing.T) {
+	records := `:020000040002F8
+:0200000ACAFE2C
+:0100100001EE
+:00000001FF
+`
+	p := NewParser(strings.NewReader(records), AllRecords())
+	var got []RawRecord
+	for p.Parse() {
+		r := p.Raw()
+		r.Data = append([]byte(nil), r.Data...)
+		got = append(got, r)
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	want := []RawRecord{
+		{Type: 4, Data: []byte{0, 2}, Checksum: 0xf8},
+		{Type: 0x0a, Data: []byte{0xca, 0xfe}, Checksum: 0x2c},
+		{Type: 0, Offset: 0x10, Address: 0x20010, Data: []byte{1}, Checksum: 0xee},
+		{Type: 1, Data: []byte{}, Checksum: 0xff},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d records, got %d", len(want), len(got))
+	}
+	for i := range got {
+		if got[i].Type != want[i].Type || got[i].Offset != want[i].Offset ||
+			got[i].Address != want[i].Address ||
+			!bytes.Equal(got[i].Data, want[i].Data) ||
+			got[i].Checksum != want[i].Checksum {
+			t.Errorf("record %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+
+	p = NewParser(strings.NewReader(records))
+	for p.Parse() {
+	}
+	if p.Err() == nil || p.Err().Error() != "line 2: invalid record type" {
+		t.Errorf("expected invalid record type, got %v", p.Err())
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+	long := ":" + strings.Repeat("0", 600) + "\n"
+	input := ":0100100001EE\n" + long + ":00000001FF\n"
+	for _, p := range []*Parser{
+		NewParser(strings.NewReader(input), MaxLineLength(100)),
+		ParseString(input, MaxLineLength(100)),
+	} {
+		for p.Parse() {
+		}
+		if p.Err() == nil || p.Err().Error() != "line 2: line too long" {
+			t.Errorf("expected line too long error, got %v", p.Err())
+		}
+	}
+
+	input = ":0100100001EE\n:" + strings.Repeat("0", 70000) + "\n"
+	p := NewParser(strings.NewReader(input))
+	for p.Parse() {
+	}
+	if p.Err() == nil || p.Err().Error() != "line 2: line too long" {
+		t.Errorf("expected line too long error, got %v", p.Err())
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	records := `:0100100001EE
+:0100110004EB
+junk
+:0100120005E8
+:00000001FF
+:00000001FF
+`
+	p := NewParser(strings.NewReader(records), CollectErrors())
+	n := 0
+	for p.Parse() {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("expected 2 records, got %d", n)
+	}
+	want := []string{
+		"line 2: invalid checksum: stored EB, computed EA (address 00000011-00000011)",
+		"line 3: missing record mark",
+		"line 5: record after end",
+	}
+	errs := p.Errors()
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i := range errs {
+		if errs[i].Error() != want[i] {
+			t.Errorf("expected %q, got %q", want[i], errs[i].Error())
+		}
+	}
+	if p.Err() == nil || p.Err().Error() != want[0] {
+		t.Errorf("expected Err to return %q, got %v", want[0], p.Err())
+	}
+	if p.Parse() || len(p.Errors()) != len(want) {
+		t.Error("Parse continued after the end of input")
+	}
+	if m := p.Metrics(); m.Errors != 3 {
+		t.Errorf("expected 3 errors in metrics, got %d", m.Errors)
+	}
+}
+
+// benchInput returns the text of a HEX file with n data records of 32
+// bytes each.
+func benchInput(n int) []byte {
+	var b bytes.Buffer
+	w := NewWriter(&b, RecordLength(32))
+	data := make([]byte, 32*n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	w.WriteData(0, data)
+	w.Close()
+	return b.Bytes()
+}
+
+// TestNonDataLines checks that functions that read data records from a
+// Parser skip the other lines returned with the Tee and AllRecords
+// options.
+func TestNonDataLines(t *testing.T) {
+	input := "comment\n:0400000001020304F2\n:020000040001F9\n" +
+		":0100000041BE\n:0400000500000100F6\n:00000001FF\n"
+	ref := bytes.NewReader([]byte{1, 2, 3, 4})
+	consumers := map[string]func(p *Parser) string{
+		"Apply": func(p *Parser) string {
+			var recs []Record
+			err := Apply(p, Offset(1), func(r Record) error {
+				recs = append(recs, Record{r.Address, bytes.Clone(r.Bytes)})
+				return nil
+			})
+			return fmt.Sprint(recs, err)
+		},
+		"ExportGDB": func(p *Parser) string {
+			var b strings.Builder
+			err := ExportGDB(&b, p)
+			return fmt.Sprint(b.String(), err)
+		},
+		"Image.Load": func(p *Parser) string {
+			var img Image
+			err := img.Load(p)
+			return fmt.Sprint(img.Segments(), err)
+		},
+		"NewContiguousReader": func(p *Parser) string {
+			b, err := io.ReadAll(NewContiguousReader(p))
+			return fmt.Sprint(b, err)
+		},
+		"ReadLineMap": func(p *Parser) string {
+			m, err := ReadLineMap("f", p)
+			span, ok := m.Lookup(0x10000)
+			return fmt.Sprint(span, ok, err)
+		},
+		"ReadUsageMap": func(p *Parser) string {
+			m, err := ReadUsageMap(p, 1, 0xff)
+			var b strings.Builder
+			m.WriteText(&b, 8)
+			return fmt.Sprint(b.String(), err)
+		},
+		"Verify": func(p *Parser) string {
+			return fmt.Sprint(Verify(p, ref, 0xff), p.Err())
+		},
+	}
+	for name, consume := range consumers {
+		want := consume(ParseString(input, NonRecordLines(SkipLines)))
+		for _, opt := range []Option{Tee(), AllRecords()} {
+			p := ParseString(input, NonRecordLines(SkipLines), opt)
+			if got := consume(p); got != want {
+				t.Errorf("%s: expected %s, got %s", name, want, got)
+			}
+		}
+	}
+	for _, opt := range []Option{Tee(), AllRecords()} {
+		recs, err := ParseRecords([]byte(input), NonRecordLines(SkipLines), opt)
+		if err != nil || len(recs) != 2 {
+			t.Errorf("ParseRecords: expected 2 records, got %v %v", recs, err)
+		}
+		m := NewMultiParser([]io.Reader{strings.NewReader(input)},
+			NonRecordLines(SkipLines), opt)
+		n := 0
+		for m.Parse() {
+			n++
+		}
+		if m.Err() != nil || n != 2 {
+			t.Errorf("MultiParser: expected 2 records, got %d %v", n, m.Err())
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	input := "junk\r\n:0300300002337A1E\r\n\n:0100000041BE\n:00000001FF\n"
+	b := []byte(input)
+	for _, byteInput := range []bool{false, true} {
+		var p *Parser
+		if byteInput {
+			p = ParseBytes(b, NonRecordLines(SkipLines))
+		} else {
+			p = NewParser(strings.NewReader(input), NonRecordLines(SkipLines))
+		}
+		var offsets []int64
+		for p.Parse() {
+			off := p.Offset()
+			offsets = append(offsets, off)
+			if !strings.HasPrefix(input[off:], string(p.Line())) {
+				t.Errorf("line at offset %d is %q", off, p.Line())
+			}
+			if byteInput && &p.Line()[0] != &b[off] {
+				t.Errorf("line at offset %d is not part of the input", off)
+			}
+		}
+		if p.Err() != nil {
+			t.Fatal(p.Err())
+		}
+		if len(offsets) != 2 || offsets[0] != 6 || offsets[1] != 26 {
+			t.Errorf("expected offsets [6 26], got %v", offsets)
+		}
+	}
+}
+
+func TestInvalidHex(t *testing.T) {
+	p := ParseString(":0300300002337A1G\n:00000001FF\n")
+	for p.Parse() {
+	}
+	var e hex.InvalidByteError
+	if !errors.As(p.Err(), &e) || e != 'G' {
+		t.Errorf("expected invalid byte 'G', got %v", p.Err())
+	}
+}
+
+// TestRecordEnds checks that records decoded whole, without searching
+// for the end of the line, are split into lines and reported the same
+// as those read field by field.
+func TestRecordEnds(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{":0100000041BE\r\n:00000001FF", ""},
+		{":0100000041BE\r:00000001FF\r", ""},
+		{":0100000041BEx\n:00000001FF\n", "line 1: trailing data"},
+		{":0100000041\rBE\n:00000001FF\n", "line 1: record too short: " +
+			"expected 2 bytes, found 1 (column 12)"},
+		{":0100000041BF\n:00000001FF\n", "line 1: invalid checksum: " +
+			"stored BF, computed BE (address 00000000-00000000)"},
+		{":0100000041BE\n:0000000\n1FF\n", "line 2: record too short (column 8)"},
+	}
+	for _, tt := range tests {
+		for _, p := range []*Parser{
+			ParseBytes([]byte(tt.input)),
+			NewParser(strings.NewReader(tt.input)),
+		} {
+			for p.Parse() {
+			}
+			if err := p.Err(); (err == nil && tt.err != "") ||
+				(err != nil && err.Error() != tt.err) {
+				t.Errorf("%q: expected error %q, got %v", tt.input, tt.err, err)
+			}
+		}
+	}
+}
+
+func TestParseAllocs(t *testing.T) {
+	allocs := func(n int) float64 {
+		input := benchInput(n)
+		return testing.AllocsPerRun(10, func() {
+			p := ParseBytes(input)
+			for p.Parse() {
+			}
+		})
+	}
+	if few, many := allocs(10), allocs(1000); many != few {
+		t.Errorf("expected no allocations per record, got %v for 10 "+
+			"records and %v for 1000", few, many)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	input := benchInput(10000)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		p := NewParser(bytes.NewReader(input))
+		for p.Parse() {
+		}
+		if p.Err() != nil {
+			b.Fatal(p.Err())
+		}
+	}
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	input := benchInput(10000)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		p := ParseBytes(input)
+		for p.Parse() {
+		}
+		if p.Err() != nil {
+			b.Fatal(p.Err())
+		}
+	}
+}
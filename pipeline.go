@@ -0,0 +1,353 @@
+package ihex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// A Pipeline describes how to build firmware files from others: the
+// inputs are read and merged into an Image, which is transformed, has
+// checksums stored in it, and is written to each of the outputs. It is
+// usually read from a JSON file by ReadPipeline, such as
+//
+//	{
+//		"inputs": [
+//			{"file": "boot.hex"},
+//			{"file": "app.bin", "format": "bin", "base": 32768}
+//		],
+//		"transform": "crop(0, 0x20000)",
+//		"checksums": [
+//			{"algo": "crc32", "start": 0, "end": 131068, "address": 131068}
+//		],
+//		"outputs": [
+//			{"file": "image.hex"},
+//			{"file": "image.bin", "format": "bin"}
+//		]
+//	}
+type Pipeline struct {
+	Inputs []PipelineInput `json:"inputs"`
+	// Policy resolves conflicts between inputs: "error" (the default),
+	// "first", "last", or "overlap".
+	Policy string `json:"policy,omitempty"`
+	// Transform is applied to the merged data, in the syntax of
+	// ParseTransform.
+	Transform string             `json:"transform,omitempty"`
+	Checksums []PipelineChecksum `json:"checksums,omitempty"`
+	Outputs   []PipelineOutput   `json:"outputs"`
+}
+
+// A PipelineInput is a file read by a Pipeline.
+type PipelineInput struct {
+	File string `json:"file"`
+	// Format is "hex" (the default) for Intel HEX, "bin" for a flat
+	// binary image loaded at Base, "auto" to choose by DetectCodec, or
+	// the name of any other registered Codec.
+	Format string `json:"format,omitempty"`
+	Base   uint32 `json:"base,omitempty"`
+	// Transform is applied to the data of this input before it is
+	// merged, in the syntax of ParseTransform.
+	Transform string `json:"transform,omitempty"`
+}
+
+// A PipelineChecksum is a checksum that a Pipeline computes over the
+// addresses in the range [Start, End) and stores at Address.
+type PipelineChecksum struct {
+	// Algo is "sum", "crc16", or "crc32", as computed by SumBytes,
+	// CRC16CCITT, or CRC32.
+	Algo    string `json:"algo"`
+	Start   uint32 `json:"start"`
+	End     uint32 `json:"end"`
+	Address uint32 `json:"address"`
+	// Fill is used for addresses without data; the default is 0xFF.
+	Fill *byte `json:"fill,omitempty"`
+	// Size is the number of bytes stored, from the low end of the
+	// checksum; the default is the size of the checksum.
+	Size int `json:"size,omitempty"`
+	// LittleEndian stores the checksum with its least significant byte
+	// first, instead of its most significant byte.
+	LittleEndian bool `json:"little_endian,omitempty"`
+}
+
+// A PipelineOutput is a file written by a Pipeline.
+type PipelineOutput struct {
+	File string `json:"file"`
+	// Format is "hex" (the default) for Intel HEX, "bin" for a flat
+	// binary image of the data, starting at its lowest address, or
+	// "map" for a manifest of its segments, as written by MarshalMap, or
+	// the name of any other registered Codec.
+	Format string `json:"format,omitempty"`
+	// Transform is applied to a copy of the data written to this output
+	// only, in the syntax of ParseTransform, so that outputs can hold
+	// different parts of the data, such as banks or flash and EEPROM.
+	Transform string `json:"transform,omitempty"`
+	// RecordLength sets the length of Intel HEX data records; the
+	// default is 16.
+	RecordLength int `json:"record_length,omitempty"`
+	// Fill is used for gaps in a binary image; the default is 0xFF.
+	Fill *byte `json:"fill,omitempty"`
+}
+
+// ReadPipeline reads a Pipeline in JSON form from r. Unknown fields are
+// an error, so that mistakes in a configuration are not ignored.
+func ReadPipeline(r io.Reader) (*Pipeline, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var pl Pipeline
+	if err := dec.Decode(&pl); err != nil {
+		return nil, err
+	}
+	return &pl, nil
+}
+
+// An OutputFS is a destination for the files written by a Pipeline,
+// such as a directory, or a map in memory for tests.
+type OutputFS interface {
+	// WriteFile writes a complete file, replacing any file of the same
+	// name. The name is a slash-separated path, as used by fs.FS.
+	WriteFile(name string, data []byte) error
+}
+
+// DirOutput returns an OutputFS that writes files within the directory
+// dir, creating any subdirectories they need.
+func DirOutput(dir string) OutputFS {
+	return dirOutput(dir)
+}
+
+type dirOutput string
+
+func (dir dirOutput) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	name = filepath.Join(string(dir), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0666)
+}
+
+// RunPipelineFile reads a Pipeline from the named JSON file and runs it,
+// with the files it names relative to the directory of the file.
+func RunPipelineFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	pl, err := ReadPipeline(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return pl.Run(filepath.Dir(name))
+}
+
+var pipelinePolicies = map[string]ConflictPolicy{
+	"":        ConflictError,
+	"error":   ConflictError,
+	"first":   FirstWins,
+	"last":    LastWins,
+	"overlap": OverlapError,
+}
+
+var pipelineAlgos = map[string]func(fill byte) ChecksumAlgo{
+	"sum":   SumBytes,
+	"crc16": CRC16CCITT,
+	"crc32": CRC32,
+}
+
+// Run runs the Pipeline, with the names of relative input and output
+// files taken as relative to dir. Each output is built in memory before
+// it is written, so an error does not leave a partial file.
+func (pl *Pipeline) Run(dir string) error {
+	path := func(name string) string {
+		if filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
+	return pl.run(func(name string) ([]byte, error) {
+		return os.ReadFile(path(name))
+	}, func(name string, b []byte) error {
+		return os.WriteFile(path(name), b, 0666)
+	})
+}
+
+// RunFS is like Run, but reads the input files from src and writes the
+// output files to dst, so that a build tool can capture every output in
+// one call, or test a Pipeline without touching the disk. File names
+// are slash-separated paths, as used by fs.FS.
+func (pl *Pipeline) RunFS(src fs.FS, dst OutputFS) error {
+	return pl.run(func(name string) ([]byte, error) {
+		return fs.ReadFile(src, name)
+	}, dst.WriteFile)
+}
+
+// run runs the Pipeline, reading and writing files with the functions
+// given.
+func (pl *Pipeline) run(read func(name string) ([]byte, error),
+	write func(name string, b []byte) error) error {
+	policy, ok := pipelinePolicies[pl.Policy]
+	if !ok {
+		return fmt.Errorf("unknown policy %q", pl.Policy)
+	}
+	img := &Image{}
+	for _, in := range pl.Inputs {
+		b, err := read(in.File)
+		var src *Image
+		if err == nil {
+			src, err = in.read(b)
+		}
+		if err == nil {
+			err = img.Merge(src, policy)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", in.File, err)
+		}
+	}
+	if err := transformImage(img, pl.Transform); err != nil {
+		return err
+	}
+	for _, c := range pl.Checksums {
+		if err := c.store(img); err != nil {
+			return fmt.Errorf("checksum at %08X: %v", c.Address, err)
+		}
+	}
+	for _, out := range pl.Outputs {
+		b, err := out.encode(img)
+		if err == nil {
+			err = write(out.File, b)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", out.File, err)
+		}
+	}
+	return nil
+}
+
+// transformImage applies the transform described by expr, if any, to
+// img.
+func transformImage(img *Image, expr string) error {
+	if expr == "" {
+		return nil
+	}
+	t, err := ParseTransform(expr)
+	if err != nil {
+		return fmt.Errorf("invalid transform: %v", err)
+	}
+	return img.Transform(t)
+}
+
+// read returns an Image of the input, with contents b.
+func (in *PipelineInput) read(b []byte) (*Image, error) {
+	img := &Image{}
+	switch in.Format {
+	case "", "hex":
+		if err := img.Load(ParseBytes(b)); err != nil {
+			return nil, err
+		}
+	case "bin":
+		if _, err := img.WriteAt(b, int64(in.Base)); err != nil {
+			return nil, err
+		}
+	default:
+		name := in.Format
+		if name == "auto" {
+			name = ""
+		}
+		c, err := findCodec(bufio.NewReader(bytes.NewReader(b)), name)
+		if err != nil {
+			return nil, err
+		}
+		if img, err = c.Read(bytes.NewReader(b)); err != nil {
+			return nil, err
+		}
+	}
+	if err := transformImage(img, in.Transform); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func (c *PipelineChecksum) store(img *Image) error {
+	newAlgo, ok := pipelineAlgos[c.Algo]
+	if !ok {
+		return fmt.Errorf("unknown algorithm %q", c.Algo)
+	}
+	fill := byte(0xff)
+	if c.Fill != nil {
+		fill = *c.Fill
+	}
+	algo := newAlgo(fill)
+	size := c.Size
+	if size == 0 {
+		size = algo.Hash().Size()
+	}
+	if size < 1 || size > 8 {
+		return fmt.Errorf("invalid size %d", size)
+	}
+	sum, err := img.Checksum(c.Start, c.End, algo)
+	if err != nil {
+		return err
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], sum)
+	stored := b[8-size:]
+	if c.LittleEndian {
+		stored = binary.LittleEndian.AppendUint64(nil, sum)[:size]
+	}
+	_, err = img.WriteAt(stored, int64(c.Address))
+	return err
+}
+
+// encode returns the contents of the output for img.
+func (out *PipelineOutput) encode(img *Image) ([]byte, error) {
+	if out.Transform != "" {
+		cp := &Image{start: img.start}
+		for _, seg := range img.segs {
+			cp.segs = append(cp.segs, Record{seg.Address, bytes.Clone(seg.Bytes)})
+		}
+		if err := transformImage(cp, out.Transform); err != nil {
+			return nil, err
+		}
+		img = cp
+	}
+	var buf bytes.Buffer
+	switch out.Format {
+	case "", "hex":
+		var opts []WriterOption
+		if out.RecordLength != 0 {
+			opts = append(opts, RecordLength(out.RecordLength))
+		}
+		hw := NewWriter(&buf, opts...)
+		if err := hw.WriteImage(img); err != nil {
+			return nil, err
+		}
+		if err := hw.Close(); err != nil {
+			return nil, err
+		}
+	case "bin":
+		fill := byte(0xff)
+		if out.Fill != nil {
+			fill = *out.Fill
+		}
+		buf.Write(img.Bytes(fill))
+	case "map":
+		return img.MarshalMap()
+	default:
+		c, ok := LookupCodec(out.Format)
+		if !ok {
+			return nil, fmt.Errorf("unknown format %q", out.Format)
+		}
+		if err := c.Write(&buf, img); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
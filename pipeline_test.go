@@ -0,0 +1,149 @@
+package ihex
+
+import (
+	"bytes"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPipeline(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"boot.hex": ":0400000001020304F2\n:00000001FF\n",
+		"app.bin":  "\x05\x06\x07\x08\x09",
+		"pipeline.json": `{
+			"inputs": [
+				{"file": "boot.hex"},
+				{"file": "app.bin", "format": "bin", "base": 16,
+					"transform": "crop(16, 20)"}
+			],
+			"transform": "offset(0x100)",
+			"checksums": [
+				{"algo": "crc32", "start": 256, "end": 276, "address": 276},
+				{"algo": "sum", "start": 256, "end": 260, "address": 280,
+					"size": 2, "little_endian": true}
+			],
+			"outputs": [
+				{"file": "out.hex", "record_length": 8},
+				{"file": "out.bin", "format": "bin", "fill": 0}
+			]
+		}`,
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RunPipelineFile(filepath.Join(dir, "pipeline.json")); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	want := []byte{1, 2, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5, 6, 7, 8}
+	// the checksum uses the default fill value for the gap
+	filled := bytes.Clone(want)
+	for i := 4; i < 16; i++ {
+		filled[i] = 0xff
+	}
+	crc := crc32.ChecksumIEEE(filled)
+	want = append(want, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+	want = append(want, 10, 0)
+	bin, err := os.ReadFile(filepath.Join(dir, "out.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bin, want) {
+		t.Errorf("expected % X, got % X", want, bin)
+	}
+	img, err := os.ReadFile(filepath.Join(dir, "out.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := MustParseRecords(img)
+	if len(recs) != 3 || recs[0].Address != 0x100 || recs[1].Address != 0x110 ||
+		!bytes.Equal(recs[1].Bytes, want[16:24]) {
+		t.Errorf("unexpected records %v", recs)
+	}
+
+	for _, tt := range []struct{ config, err string }{
+		{`{"inputs": [], "bogus": 1}`, `json: unknown field "bogus"`},
+		{`{"policy": "any"}`, `unknown policy "any"`},
+		{`{"inputs": [{"file": "boot.hex", "format": "elf"}]}`,
+			`boot.hex: unknown format "elf"`},
+		{`{"inputs": [{"file": "boot.hex"}, {"file": "app.bin", "format": "bin"}]}`,
+			"app.bin: conflicting data at 00000000"},
+		{`{"transform": "shift(1)"}`, "invalid transform: "},
+		{`{"checksums": [{"algo": "md5"}]}`,
+			`checksum at 00000000: unknown algorithm "md5"`},
+		{`{"checksums": [{"algo": "sum", "size": 9}]}`,
+			"checksum at 00000000: invalid size 9"},
+		{`{"outputs": [{"file": "x", "format": "srec"}]}`, `x: unknown format "srec"`},
+	} {
+		pl, err := ReadPipeline(strings.NewReader(tt.config))
+		if err == nil {
+			err = pl.Run(dir)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected %q, got %v", tt.config, tt.err, err)
+		}
+	}
+}
+
+// memOutput is an OutputFS that holds files in memory.
+type memOutput map[string][]byte
+
+func (m memOutput) WriteFile(name string, data []byte) error {
+	m[name] = data
+	return nil
+}
+
+func TestPipelineFS(t *testing.T) {
+	src := fstest.MapFS{
+		"in/fw.hex": {Data: []byte(
+			":0400000001020304F2\n:0400100005060708D2\n:00000001FF\n")},
+	}
+	pl, err := ReadPipeline(strings.NewReader(`{
+		"inputs": [{"file": "in/fw.hex"}],
+		"outputs": [
+			{"file": "bank0.bin", "format": "bin",
+				"transform": "crop(0, 0x10)"},
+			{"file": "bank1.bin", "format": "bin",
+				"transform": "crop(0x10, 0x20) | offset(-0x10)"},
+			{"file": "out/fw.map", "format": "map"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := memOutput{}
+	if err := pl.RunFS(src, dst); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(dst) != 3 {
+		t.Errorf("expected 3 outputs, got %d", len(dst))
+	}
+	if want := []byte{1, 2, 3, 4}; !bytes.Equal(dst["bank0.bin"], want) {
+		t.Errorf("bank0: expected % X, got % X", want, dst["bank0.bin"])
+	}
+	if want := []byte{5, 6, 7, 8}; !bytes.Equal(dst["bank1.bin"], want) {
+		t.Errorf("bank1: expected % X, got % X", want, dst["bank1.bin"])
+	}
+	if !strings.HasPrefix(string(dst["out/fw.map"]), `{"segments":[{"start":0,`) {
+		t.Errorf("unexpected manifest %s", dst["out/fw.map"])
+	}
+
+	dir := t.TempDir()
+	if err := pl.RunFS(src, DirOutput(dir)); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "out", "fw.map"))
+	if err != nil || !bytes.Equal(b, dst["out/fw.map"]) {
+		t.Errorf("expected the manifest in a subdirectory, got %v", err)
+	}
+	if err := DirOutput(dir).WriteFile("../x", nil); err == nil {
+		t.Error("expected error for a name outside the directory")
+	}
+}
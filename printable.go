@@ -0,0 +1,44 @@
+package ihex
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// A PrintableString is a run of printable text found in an Image.
+type PrintableString struct {
+	Address uint32
+	Text    string
+}
+
+// Strings returns the runs of at least minLen printable characters in
+// the Image, in address order, like the strings command does for a
+// file. Characters are printable ASCII or UTF-8 characters, or tabs. A
+// run never spans a gap between segments.
+func (img *Image) Strings(minLen int) []PrintableString {
+	var found []PrintableString
+	for _, seg := range img.segs {
+		start, n := 0, 0 // offset and length in characters of the run
+		flush := func(end int) {
+			if n >= minLen && n > 0 {
+				found = append(found, PrintableString{
+					Address: seg.Address + uint32(start),
+					Text:    string(seg.Bytes[start:end]),
+				})
+			}
+		}
+		for i := 0; i < len(seg.Bytes); {
+			r, size := utf8.DecodeRune(seg.Bytes[i:])
+			if (r == utf8.RuneError && size == 1) ||
+				(!unicode.IsPrint(r) && r != '\t') {
+				flush(i)
+				start, n = i+size, 0
+			} else {
+				n++
+			}
+			i += size
+		}
+		flush(len(seg.Bytes))
+	}
+	return found
+}
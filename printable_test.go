@@ -0,0 +1,24 @@
+package ihex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	var img Image
+	img.WriteAt([]byte("\x00boot v1.2\x00\xffab\x00h\xc3\xa9llo\twor"), 0x100)
+	img.WriteAt([]byte("ld\x00"), 0x200)
+	want := []PrintableString{
+		{0x101, "boot v1.2"},
+		{0x10c, "ab"},
+		{0x10f, "héllo\twor"},
+		{0x200, "ld"},
+	}
+	if got := img.Strings(2); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := img.Strings(4); len(got) != 2 {
+		t.Errorf("expected 2 strings of at least 4 characters, got %+v", got)
+	}
+}
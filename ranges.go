@@ -0,0 +1,54 @@
+package ihex
+
+// KeepRange makes the Parser keep only the data with addresses in the
+// range [start, end), as if by the Crop transform. Data records outside
+// of the range are not returned by Parse, and those that straddle it
+// are trimmed.
+func KeepRange(start, end uint32) Option {
+	return func(p *Parser) {
+		p.transforms = append(p.transforms, Crop(start, end))
+	}
+}
+
+// DropRange makes the Parser drop the data with addresses in the range
+// [start, end), as if by the Exclude transform.
+func DropRange(start, end uint32) Option {
+	return func(p *Parser) {
+		p.transforms = append(p.transforms, Exclude(start, end))
+	}
+}
+
+// Relocate makes the Parser add delta to the address of each data
+// record, as if by the Offset transform. It is an error for a record to
+// be moved outside of the 32-bit address space.
+//
+// KeepRange, DropRange, and Relocate are applied in the order they are
+// given, so the ranges of options that follow Relocate are in terms of
+// the relocated addresses, and several KeepRange options keep only the
+// intersection of their ranges.
+func Relocate(delta int64) Option {
+	return func(p *Parser) {
+		p.transforms = append(p.transforms, Offset(delta))
+	}
+}
+
+// transformData passes the current data record through the transforms
+// given by any KeepRange, DropRange, and Relocate options, replacing it
+// with the first record that results and queueing any others. It
+// returns false if no records result.
+func (p *Parser) transformData() bool {
+	if len(p.transforms) == 0 {
+		return true
+	}
+	recs, err := Chain(p.transforms...).Next(p.data)
+	if err != nil {
+		p.err = p.makeError(err.Error())
+		return false
+	}
+	if len(recs) == 0 {
+		return false
+	}
+	p.data = recs[0]
+	p.queue = recs[1:]
+	return true
+}
@@ -0,0 +1,56 @@
+package ihex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRangeOptions(t *testing.T) {
+	records := `:050010000102030405DC
+:0100200006D9
+:00000001FF
+`
+	tests := []struct {
+		opts []Option
+		want []Record
+	}{
+		{[]Option{DropRange(0x12, 0x14)}, []Record{
+			{0x10, []byte{1, 2}},
+			{0x14, []byte{5}},
+			{0x20, []byte{6}},
+		}},
+		{[]Option{KeepRange(0x11, 0x21), Relocate(-0x10)}, []Record{
+			{0x01, []byte{2, 3, 4, 5}},
+			{0x10, []byte{6}},
+		}},
+		{[]Option{KeepRange(0x30, 0x40)}, nil},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(records), tt.opts...)
+		var got []Record
+		for p.Parse() {
+			got = append(got, Record{p.Data().Address, bytes.Clone(p.Data().Bytes)})
+		}
+		if p.Err() != nil {
+			t.Fatal("unexpected error", p.Err())
+		}
+		checkRecords(t, got, tt.want)
+	}
+
+	p := NewParser(strings.NewReader(records), Relocate(-0x20))
+	for p.Parse() {
+	}
+	if p.Err() == nil || !strings.HasPrefix(p.Err().Error(), "line 1: offset -32") {
+		t.Errorf("expected relocation error, got %v", p.Err())
+	}
+
+	p = NewParser(strings.NewReader(records), Tee(), KeepRange(0x20, 0x30))
+	var data []bool
+	for p.Parse() {
+		data = append(data, p.HasData())
+	}
+	if len(data) != 3 || data[0] || !data[1] || data[2] {
+		t.Errorf("expected only line 2 to have data, got %v", data)
+	}
+}
@@ -0,0 +1,166 @@
+package ihex
+
+import (
+	"fmt"
+	"io"
+)
+
+type contiguousReader struct {
+	p       *Parser
+	buf     []byte
+	next    uint32
+	started bool
+	err     error
+
+	fill    bool
+	value   byte
+	gap     uint64 // number of fill bytes still to be read
+	pending Record // the record that follows the gap
+}
+
+// NewContiguousReader returns an io.Reader that reads the data bytes
+// from the data records read by p. The records must be contiguous, each
+// starting at the address following the end of the previous one;
+// otherwise reading fails with an error that gives the addresses at
+// which the data are not contiguous. An error from p is returned as is.
+func NewContiguousReader(p *Parser) io.Reader {
+	return &contiguousReader{p: p}
+}
+
+// NewFlatReader returns an io.Reader that reads the data from the
+// Intel HEX file read from r as a flat binary image, starting at the
+// address of the first data record, with any gaps between records
+// filled with fill. The image is produced as the file is parsed, so
+// the data records must be in ascending address order; otherwise
+// reading fails with an error.
+func NewFlatReader(r io.Reader, fill byte) io.Reader {
+	return &contiguousReader{p: NewParser(r), fill: true, value: fill}
+}
+
+func (r *contiguousReader) Read(b []byte) (int, error) {
+	if r.gap > 0 {
+		return r.readGap(b), nil
+	}
+	if r.pending.Bytes != nil {
+		r.buf = r.pending.Bytes
+		r.pending = Record{}
+	}
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if !r.p.Parse() {
+			r.err = r.p.Err()
+			if r.err == nil {
+				r.err = io.EOF
+			}
+			continue
+		}
+		if !r.p.HasData() {
+			continue
+		}
+		data := r.p.Data()
+		if r.started && data.Address > r.next && r.fill {
+			r.gap = uint64(data.Address - r.next)
+			r.next = data.Address + uint32(len(data.Bytes))
+			r.pending = data
+			return r.readGap(b), nil
+		}
+		if r.started && data.Address != r.next {
+			if data.Address > r.next {
+				r.err = fmt.Errorf("gap in data from %08X to %08X",
+					r.next, data.Address-1)
+			} else {
+				r.err = fmt.Errorf("data at %08X out of order "+
+					"(expected %08X)", data.Address, r.next)
+			}
+			continue
+		}
+		r.started = true
+		r.next = data.Address + uint32(len(data.Bytes))
+		r.buf = data.Bytes
+	}
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// readGap reads fill bytes into b, up to the size of the gap.
+func (r *contiguousReader) readGap(b []byte) int {
+	n := int(min(uint64(len(b)), r.gap))
+	for i := range b[:n] {
+		b[i] = r.value
+	}
+	r.gap -= uint64(n)
+	return n
+}
+
+// A GapPolicy determines how the Reader of an Image treats the gaps
+// between its segments.
+type GapPolicy int
+
+const (
+	GapError GapPolicy = iota // stop with an error at the first gap
+	GapFill                   // fill gaps with a fill byte
+	GapSkip                   // leave gaps out
+)
+
+// An imageReader reads the data in an Image in address order.
+type imageReader struct {
+	segs   spans
+	policy GapPolicy
+	fill   byte
+	next   uint64 // the address after the last byte read
+	off    int    // the number of bytes read from segs[0]
+	gap    uint64 // the number of fill bytes still to be read
+}
+
+// Reader returns an io.Reader that reads the data in the Image in
+// address order, from its lowest address to its highest, treating the
+// gaps between segments according to policy; fill is only used with
+// GapFill. The Image must not be modified while the Reader is in use.
+func (img *Image) Reader(policy GapPolicy, fill byte) io.Reader {
+	r := &imageReader{segs: img.segs, policy: policy, fill: fill}
+	if len(img.segs) > 0 {
+		r.next = uint64(img.segs[0].Address)
+	}
+	return r
+}
+
+func (r *imageReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for r.gap == 0 {
+		if len(r.segs) == 0 {
+			return 0, io.EOF
+		}
+		seg := r.segs[0]
+		if r.off == len(seg.Bytes) {
+			r.segs, r.off = r.segs[1:], 0
+			continue
+		}
+		start := uint64(seg.Address)
+		if r.off == 0 && start > r.next {
+			switch r.policy {
+			case GapError:
+				return 0, fmt.Errorf("gap in data from %08X to %08X",
+					r.next, start-1)
+			case GapFill:
+				r.gap = start - r.next
+				r.next = start
+				continue
+			}
+		}
+		n := copy(b, seg.Bytes[r.off:])
+		r.off += n
+		r.next = start + uint64(r.off)
+		return n, nil
+	}
+	n := int(min(uint64(len(b)), r.gap))
+	for i := range b[:n] {
+		b[i] = r.fill
+	}
+	r.gap -= uint64(n)
+	return n, nil
+}
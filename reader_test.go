@@ -0,0 +1,123 @@
+package ihex
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestContiguousReader(t *testing.T) {
+	records := `
+:02FFFE000102FE
+:020000040001F9
+:0100000003FC
+:0100050004F6
+:00000001FF
+`
+	r := NewContiguousReader(ParseString(records))
+	b, err := io.ReadAll(r)
+	if err == nil || err.Error() != "gap in data from 00010001 to 00010004" {
+		t.Error("missed gap", err)
+	}
+	if string(b) != "\x01\x02\x03" {
+		t.Errorf("incorrect data before gap %q", b)
+	}
+
+	records = `
+:040010006164647251
+:070014006573732067617042
+:00000001FF
+`
+	r = NewContiguousReader(ParseString(records))
+	b, err = io.ReadAll(r)
+	if err != nil || string(b) != "address gap" {
+		t.Errorf("unexpected result %q %v", b, err)
+	}
+
+	records = `
+:070014006573732067617042
+:040010006164647251
+:00000001FF
+`
+	r = NewContiguousReader(ParseString(records))
+	_, err = io.ReadAll(r)
+	if err == nil ||
+		err.Error() != "data at 00000010 out of order (expected 0000001B)" {
+		t.Error("missed out of order data", err)
+	}
+
+	r = NewContiguousReader(ParseString(":00000001FE"))
+	if _, err = io.ReadAll(r); err == nil {
+		t.Error("missed parse error")
+	}
+}
+
+func TestFlatReader(t *testing.T) {
+	records := `:0100100001EE
+:0100130002EA
+:0100140003E8
+:00000001FF
+`
+	r := iotest.OneByteReader(NewFlatReader(strings.NewReader(records), 0xff))
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := []byte{1, 0xff, 0xff, 2, 3}; !bytes.Equal(b, want) {
+		t.Errorf("expected % X, got % X", want, b)
+	}
+
+	records = ":0100130002EA\n:0100100001EE\n:00000001FF\n"
+	_, err = io.ReadAll(NewFlatReader(strings.NewReader(records), 0))
+	if err == nil || err.Error() != "data at 00000010 out of order (expected 00000014)" {
+		t.Errorf("expected out of order error, got %v", err)
+	}
+}
+
+func TestImageReader(t *testing.T) {
+	img := MustReadImage([]byte(":0401000001020304F1\n:020106000506EC\n" +
+		":00000001FF\n"))
+	tests := []struct {
+		policy GapPolicy
+		want   []byte
+		err    string
+	}{
+		{GapFill, []byte{1, 2, 3, 4, 0xee, 0xee, 5, 6}, ""},
+		{GapSkip, []byte{1, 2, 3, 4, 5, 6}, ""},
+		{GapError, []byte{1, 2, 3, 4}, "gap in data from 00000104 to 00000105"},
+	}
+	for _, tt := range tests {
+		got, err := io.ReadAll(io.LimitReader(img.Reader(tt.policy, 0xee), 100))
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%d: expected error %q, got %v", tt.policy, tt.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%d: unexpected error %v", tt.policy, err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%d: expected % X, got % X", tt.policy, tt.want, got)
+		}
+		// read a byte at a time to cross every boundary
+		r := img.Reader(tt.policy, 0xee)
+		var one [1]byte
+		got = nil
+		for {
+			n, err := r.Read(one[:])
+			got = append(got, one[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%d: byte at a time: expected % X, got % X",
+				tt.policy, tt.want, got)
+		}
+	}
+
+	if b, err := io.ReadAll(new(Image).Reader(GapError, 0)); err != nil || len(b) != 0 {
+		t.Error("unexpected result for empty Image", b, err)
+	}
+}
@@ -0,0 +1,121 @@
+package ihex
+
+import (
+	"bytes"
+	"iter"
+)
+
+// ParseRecords parses all of the data records in b, which holds the
+// complete text of an Intel HEX file. The returned records do not share
+// memory with b.
+func ParseRecords(b []byte, opts ...Option) ([]Record, error) {
+	var recs []Record
+	p := ParseBytes(b, opts...)
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		data := p.Data()
+		data.Bytes = bytes.Clone(data.Bytes)
+		recs = append(recs, data)
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// MustParseRecords is like ParseRecords but panics if b cannot be
+// parsed. It is intended for data known to be valid when the program is
+// built, such as a file embedded with a //go:embed directive and parsed
+// to initialize a package variable.
+func MustParseRecords(b []byte, opts ...Option) []Record {
+	recs, err := ParseRecords(b, opts...)
+	if err != nil {
+		panic("ihex: MustParseRecords: " + err.Error())
+	}
+	return recs
+}
+
+// MustReadImage returns an Image holding the data records in b, which
+// holds the complete text of an Intel HEX file, and panics if b cannot
+// be parsed. Like MustParseRecords, it is intended for data known to be
+// valid when the program is built.
+func MustReadImage(b []byte, opts ...Option) *Image {
+	img := &Image{}
+	if err := img.Load(ParseBytes(b, opts...)); err != nil {
+		panic("ihex: MustReadImage: " + err.Error())
+	}
+	return img
+}
+
+// Records returns an iterator over the data records read by p. If
+// parsing fails, the iterator yields the error, with a zero Record,
+// after the records read before it. As with the Data method, the
+// underlying data of each record may be overwritten once the iteration
+// continues.
+func (p *Parser) Records() iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		for p.Parse() {
+			if !p.HasData() {
+				continue
+			}
+			if !yield(p.Data(), nil) {
+				return
+			}
+		}
+		if err := p.Err(); err != nil {
+			yield(Record{}, err)
+		}
+	}
+}
+
+// Blocks returns an iterator over the data read by p, in which
+// consecutive data records that are contiguous, each starting at the
+// address following the end of the previous one, are merged into a
+// single block of at most maxLen bytes; a maxLen of zero means no
+// limit. Errors are yielded as by Records. The underlying data of each
+// block may be overwritten once the iteration continues.
+func (p *Parser) Blocks(maxLen int) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		var block Record
+		for r, err := range p.Records() {
+			if err != nil {
+				if len(block.Bytes) > 0 && !yield(block, nil) {
+					return
+				}
+				yield(Record{}, err)
+				return
+			}
+			end := block.Address + uint32(len(block.Bytes))
+			if len(block.Bytes) > 0 && r.Address != end {
+				if !yield(block, nil) {
+					return
+				}
+				block.Bytes = block.Bytes[:0]
+			}
+			if len(block.Bytes) == 0 {
+				block.Address = r.Address
+			}
+			for len(r.Bytes) > 0 {
+				n := len(r.Bytes)
+				if maxLen > 0 {
+					n = min(n, maxLen-len(block.Bytes))
+				}
+				block.Bytes = append(block.Bytes, r.Bytes[:n]...)
+				r.Bytes = r.Bytes[n:]
+				r.Address += uint32(n)
+				if len(block.Bytes) == maxLen {
+					if !yield(block, nil) {
+						return
+					}
+					block.Address = r.Address
+					block.Bytes = block.Bytes[:0]
+				}
+			}
+		}
+		if len(block.Bytes) > 0 {
+			yield(block, nil)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package ihex
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+)
+
+// A ReportTemplate is a template that RenderReport can execute, such as
+// a *template.Template from text/template or html/template.
+type ReportTemplate interface {
+	Execute(w io.Writer, data any) error
+}
+
+// RenderReport writes the report produced by executing tmpl with s, so
+// that release notes can include tables describing the layout of the
+// firmware. MarkdownReport and HTMLReport are built-in templates; others
+// can use the fields of Summary and the methods of Region.
+func RenderReport(w io.Writer, tmpl ReportTemplate, s Summary) error {
+	return tmpl.Execute(w, s)
+}
+
+// Last returns the highest address in the Region.
+func (r Region) Last() uint32 {
+	return r.Address + uint32(r.Len) - 1
+}
+
+// MarkdownReport is a template for RenderReport that writes a size
+// report as a Markdown table.
+var MarkdownReport = template.Must(template.New("markdown").Parse(
+	`| Start | End | Bytes |
+| ---: | ---: | ---: |
+{{range .Regions}}| 0x{{printf "%08X" .Address}} | 0x{{printf "%08X" .Last}} | {{.Len}} |
+{{end}}
+{{.Bytes}} bytes in {{len .Regions}} regions.
+{{- if .HasEIP}} Start address: 0x{{printf "%08X" .EIP}}.{{end}}
+`))
+
+// HTMLReport is a template for RenderReport that writes a summary as
+// an HTML table.
+var HTMLReport = htmltemplate.Must(htmltemplate.New("html").Parse(
+	`<table>
+<thead><tr><th>Start</th><th>End</th><th>Bytes</th></tr></thead>
+<tbody>
+{{range .Regions}}<tr><td>0x{{printf "%08X" .Address}}</td><td>0x{{printf "%08X" .Last}}</td><td>{{.Len}}</td></tr>
+{{end}}</tbody>
+</table>
+<p>{{.Bytes}} bytes in {{len .Regions}} regions.
+{{- if .HasEIP}} Start address: 0x{{printf "%08X" .EIP}}.{{end}}</p>
+`))
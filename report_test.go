@@ -0,0 +1,44 @@
+package ihex
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestRenderReport(t *testing.T) {
+	s, err := Summarize(strings.NewReader(":0400000001020304F2\n" +
+		":0400100005060708D2\n:0400000500000100F6\n:00000001FF\n"))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var b strings.Builder
+	if err := RenderReport(&b, MarkdownReport, s); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `| Start | End | Bytes |
+| ---: | ---: | ---: |
+| 0x00000000 | 0x00000003 | 4 |
+| 0x00000010 | 0x00000013 | 4 |
+
+8 bytes in 2 regions. Start address: 0x00000100.
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s, got\n%s", want, b.String())
+	}
+
+	b.Reset()
+	if err := RenderReport(&b, HTMLReport, s); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !strings.Contains(b.String(),
+		"<tr><td>0x00000010</td><td>0x00000013</td><td>4</td></tr>") {
+		t.Errorf("unexpected HTML\n%s", b.String())
+	}
+
+	b.Reset()
+	tmpl := template.Must(template.New("").Parse("{{.MinAddress}}-{{.MaxAddress}}"))
+	if err := RenderReport(&b, tmpl, s); err != nil || b.String() != "0-19" {
+		t.Errorf("expected 0-19, got %q %v", b.String(), err)
+	}
+}
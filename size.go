@@ -0,0 +1,27 @@
+package ihex
+
+// EstimateSize returns the exact number of bytes that a Writer
+// configured by any options given would write for the Image with
+// WriteImage and Close, without keeping the output. It returns -1 if the
+// Writer would fail, as it does for data outside of the segmented
+// address space with SegmentAddressing. Any Digests option is ignored.
+func EstimateSize(img *Image, opts ...WriterOption) int64 {
+	opts = append(append([]WriterOption(nil), opts...), Digests(nil, nil, 0))
+	var n countWriter
+	hw := NewWriter(&n, opts...)
+	if err := hw.WriteImage(img); err != nil {
+		return -1
+	}
+	if err := hw.Close(); err != nil {
+		return -1
+	}
+	return int64(n)
+}
+
+// A countWriter counts the bytes written to it.
+type countWriter int64
+
+func (n *countWriter) Write(b []byte) (int, error) {
+	*n += countWriter(len(b))
+	return len(b), nil
+}
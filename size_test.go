@@ -0,0 +1,36 @@
+package ihex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	img := &Image{}
+	img.WriteAt(make([]byte, 40), 0xfff0)
+	img.WriteAt([]byte{1}, 0x200000)
+	img.SetStartLinear(0x100)
+	for _, opts := range [][]WriterOption{
+		nil,
+		{RecordLength(7), CRLF()},
+		{AlwaysWriteBase(), LowerCase()},
+	} {
+		var b strings.Builder
+		hw := NewWriter(&b, opts...)
+		hw.WriteImage(img)
+		if err := hw.Close(); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if n := EstimateSize(img, opts...); n != int64(b.Len()) {
+			t.Errorf("expected %d, got %d", b.Len(), n)
+		}
+	}
+	var text strings.Builder
+	EstimateSize(img, Digests(&text, nil, 0))
+	if text.Len() != 0 {
+		t.Errorf("expected the text digest to be untouched")
+	}
+	if n := EstimateSize(img, SegmentAddressing()); n != -1 {
+		t.Errorf("expected -1 for data outside of address space, got %d", n)
+	}
+}
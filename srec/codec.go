@@ -0,0 +1,45 @@
+package srec
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/edmccard/ihex"
+)
+
+func init() {
+	ihex.RegisterCodec(ihex.Codec{
+		Name:  "srec",
+		Sniff: sniff,
+		Caps:  ihex.CodecStreaming | ihex.CodecStart,
+		Read:  ReadImage,
+		Write: WriteImage,
+	})
+}
+
+// sniff claims a file that starts with an S-record.
+func sniff(head []byte) int {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if len(head) > 1 && head[0] == 'S' && head[1] >= '0' && head[1] <= '9' {
+		return 10
+	}
+	return 0
+}
+
+// WriteImage writes the data in img to w as an S37 file, with its start
+// address, if it has one set by a record of type 5 or by
+// ihex.Image.SetStartLinear, in the termination record.
+func WriteImage(w io.Writer, img *ihex.Image) error {
+	sw := NewWriter(w)
+	for _, seg := range img.Segments() {
+		if err := sw.WriteData(seg.Address, seg.Bytes); err != nil {
+			return err
+		}
+	}
+	if eip, ok := img.EIP(); ok {
+		if err := sw.WriteStart(eip); err != nil {
+			return err
+		}
+	}
+	return sw.Close()
+}
@@ -0,0 +1,27 @@
+package srec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/edmccard/ihex"
+)
+
+func TestCodec(t *testing.T) {
+	const input = ":0400000001020304F2\n:0400000500000100F6\n:00000001FF\n"
+	var s strings.Builder
+	if err := ihex.Convert(&s, "srec", strings.NewReader(input), ""); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := "S3090000000001020304EC\nS5030001FB\nS70500000100F9\n"
+	if s.String() != want {
+		t.Errorf("expected\n%s, got\n%s", want, s.String())
+	}
+	var h strings.Builder
+	if err := ihex.Convert(&h, "hex", strings.NewReader(s.String()), ""); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if h.String() != input {
+		t.Errorf("expected\n%s, got\n%s", input, h.String())
+	}
+}
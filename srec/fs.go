@@ -0,0 +1,75 @@
+package srec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/edmccard/ihex"
+)
+
+// srecExts are the file name extensions of S-record files recognized by
+// ReadFS.
+var srecExts = []string{".srec", ".s19", ".s28", ".s37", ".mot"}
+
+// ReadImage reads the S-record file from r into an ihex.Image, with the
+// address from the termination record as its start address.
+func ReadImage(r io.Reader) (*ihex.Image, error) {
+	p := NewParser(r)
+	img := &ihex.Image{}
+	for p.Parse() {
+		d := p.Data()
+		img.WriteAt(d.Bytes, int64(d.Address))
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	if start, ok := p.Start(); ok {
+		img.SetStartLinear(start)
+	}
+	return img, nil
+}
+
+// ReadFS is like ihex.ReadImagesFS, but also reads every S-record file
+// that it finds, judged by a file name extension of .srec, .s19, .s28,
+// .s37, or .mot in any case. The options given apply only to the Intel
+// HEX files.
+func ReadFS(fsys fs.FS, opts ...ihex.Option) (map[string]*ihex.Image, error) {
+	files, err := ihex.ReadImagesFS(fsys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry,
+		err error) error {
+		if err != nil || d.IsDir() || !isSRecFile(name) {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		img, err := ReadImage(bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		files[name] = img
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func isSRecFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, srecExt := range srecExts {
+		if ext == srecExt {
+			return true
+		}
+	}
+	return false
+}
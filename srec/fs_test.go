@@ -0,0 +1,40 @@
+package srec
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"notes.txt":    {Data: []byte("S1 is not a record here")},
+		"boot/app.S19": {Data: []byte("S10512340102B1\nS9031234B6\n")},
+		"app.hex":      {Data: []byte(":01000000AA55\n:00000001FF\n")},
+	}
+	got, err := ReadFS(fsys)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(got))
+	}
+	b := make([]byte, 2)
+	if n, err := got["boot/app.S19"].ReadAt(b, 0x1234); n != 2 || err != nil ||
+		!bytes.Equal(b, []byte{1, 2}) {
+		t.Errorf("incorrect data % X (%d, %v)", b, n, err)
+	}
+	if eip, ok := got["boot/app.S19"].EIP(); !ok || eip != 0x1234 {
+		t.Errorf("expected start 1234, got %X (%v)", eip, ok)
+	}
+	if got["app.hex"].Size() != 1 {
+		t.Errorf("expected 1 byte of Intel HEX data")
+	}
+
+	fsys["bad.mot"] = &fstest.MapFile{Data: []byte("S10512340102B2\nS9031234B6\n")}
+	_, err = ReadFS(fsys)
+	if err == nil || err.Error() != "bad.mot: line 1: invalid checksum: "+
+		"stored B2, computed B1" {
+		t.Error("missed parse error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package srec
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/edmccard/ihex"
+)
+
+// A MixedParser reads data records from input in which groups of
+// S-record lines and Intel HEX lines follow one another, as in a file
+// made by concatenating files of both formats. It switches format at
+// each line that starts with a different record mark, and tags each
+// record with the format it was read from. Each group of S-records is
+// checked as by a Parser, except that it need not end with a
+// termination record; each group of Intel HEX lines is read by an
+// ihex.Parser, and ends after its end record, if it has one.
+type MixedParser struct {
+	scanner *bufio.Scanner
+	opts    []ihex.Option
+	srec    Parser
+	hex     *ihex.Parser
+	hexBase int
+	pending []byte
+	hexDone bool
+	buf     []byte
+	format  string
+	data    ihex.Record
+	start   uint32
+	started bool
+	line    int
+	err     error
+}
+
+// NewMixedParser returns a new MixedParser to read from r. The options
+// given configure the ihex.Parser for each group of Intel HEX lines.
+func NewMixedParser(r io.Reader, opts ...ihex.Option) *MixedParser {
+	opts = append(opts[:len(opts):len(opts)], ihex.AllowMissingEOF())
+	return &MixedParser{scanner: bufio.NewScanner(r), opts: opts}
+}
+
+// Parse reads the next data record, which can then be accessed by the
+// Data method and its format by the Format method. It returns false
+// when there are no more data records, or if an error occurred during
+// parsing; the error, if any, can be accessed by the Err method.
+func (m *MixedParser) Parse() bool {
+	for m.err == nil {
+		if m.hex != nil {
+			if m.parseHex() {
+				return true
+			}
+			continue
+		}
+		b := m.pending
+		m.pending = nil
+		if b == nil {
+			if !m.scanner.Scan() {
+				m.err = m.scanner.Err()
+				return false
+			}
+			m.line++
+			b = m.scanner.Bytes()
+		}
+		switch {
+		case len(b) == 0:
+		case b[0] == ':':
+			m.hexBase = m.line - 1
+			m.hexDone = isEndRecord(b)
+			m.buf = append(append(m.buf[:0], b...), '\n')
+			m.hex = ihex.NewParser(hexLines{m}, m.opts...)
+		default:
+			if m.format != "srec" || m.srec.ended {
+				m.srec = Parser{}
+				m.format = "srec"
+			}
+			m.srec.line = m.line
+			if m.srec.parseLine(b) {
+				m.data = m.srec.data
+				return true
+			}
+			if m.srec.err != nil {
+				m.err = m.srec.err
+			} else if start, ok := m.srec.Start(); ok {
+				m.start, m.started = start, true
+			}
+		}
+	}
+	return false
+}
+
+// parseHex reads the next record from the current group of Intel HEX
+// lines, returning true if it was a data record.
+func (m *MixedParser) parseHex() bool {
+	m.format = "hex"
+	if m.hex.Parse() {
+		if m.hex.HasData() {
+			m.data = m.hex.Data()
+			return true
+		}
+		return false
+	}
+	if err := m.hex.Err(); err != nil {
+		if pe, ok := err.(ihex.ParseError); ok {
+			pe.Line += m.hexBase
+			err = pe
+		}
+		m.err = err
+		return false
+	}
+	if eip, ok := m.hex.EIP(); ok {
+		m.start, m.started = eip, true
+	} else if cs, ip, ok := m.hex.CSIP(); ok {
+		m.start, m.started = uint32(cs)<<4+uint32(ip), true
+	}
+	m.hex = nil
+	return false
+}
+
+// hexLines is an io.Reader of the lines of a group of Intel HEX lines,
+// one line at a time so that an ihex.Parser reading from it never reads
+// past the group. It ends before a line that does not start with a
+// record mark, which is left for the MixedParser, or after an end
+// record.
+type hexLines struct {
+	m *MixedParser
+}
+
+func (h hexLines) Read(b []byte) (int, error) {
+	m := h.m
+	if len(m.buf) == 0 {
+		if m.hexDone || m.pending != nil || !m.scanner.Scan() {
+			return 0, io.EOF
+		}
+		m.line++
+		line := m.scanner.Bytes()
+		if len(line) > 0 && line[0] != ':' {
+			m.pending = append([]byte(nil), line...)
+			return 0, io.EOF
+		}
+		m.hexDone = isEndRecord(line)
+		m.buf = append(append(m.buf[:0], line...), '\n')
+	}
+	n := copy(b, m.buf)
+	m.buf = m.buf[n:]
+	return n, nil
+}
+
+// isEndRecord reports whether line is an Intel HEX end record.
+func isEndRecord(line []byte) bool {
+	return len(line) >= 9 && line[0] == ':' && string(line[7:9]) == "01"
+}
+
+// Data returns the last data record read by the Parse method. The
+// underlying data may be overwritten by subsequent calls to Parse.
+func (m *MixedParser) Data() ihex.Record {
+	return m.data
+}
+
+// Format returns the name of the format of the last record read by the
+// Parse method, "hex" or "srec", as used by the codecs of the same
+// names.
+func (m *MixedParser) Format() string {
+	return m.format
+}
+
+// Start returns the last start address read by the MixedParser, from a
+// termination record or an Intel HEX record of type 3 or 5, with ok
+// true if there was one.
+func (m *MixedParser) Start() (addr uint32, ok bool) {
+	return m.start, m.started
+}
+
+// LineNumber returns the number of the line read by the last call to
+// Parse, counting from 1.
+func (m *MixedParser) LineNumber() int {
+	return m.line
+}
+
+// Err returns the first error that was encountered by the MixedParser.
+func (m *MixedParser) Err() error {
+	return m.err
+}
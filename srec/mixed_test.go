@@ -0,0 +1,78 @@
+package srec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/edmccard/ihex"
+)
+
+func TestMixedParser(t *testing.T) {
+	input := `S10500000102F7
+S9030000FC
+:020010000304E7
+:00000001FF
+:020020000506D3
+:0400000500000100F6
+
+S104003007C4
+`
+	type tagged struct {
+		format string
+		rec    ihex.Record
+		line   int
+	}
+	want := []tagged{
+		{"srec", ihex.Record{Address: 0, Bytes: []byte{1, 2}}, 1},
+		{"hex", ihex.Record{Address: 0x10, Bytes: []byte{3, 4}}, 3},
+		{"hex", ihex.Record{Address: 0x20, Bytes: []byte{5, 6}}, 5},
+		{"srec", ihex.Record{Address: 0x30, Bytes: []byte{7}}, 8},
+	}
+	m := NewMixedParser(strings.NewReader(input))
+	var got []tagged
+	for m.Parse() {
+		d := m.Data()
+		got = append(got, tagged{m.Format(),
+			ihex.Record{Address: d.Address, Bytes: bytes.Clone(d.Bytes)},
+			m.LineNumber()})
+	}
+	if m.Err() != nil {
+		t.Fatal("unexpected error", m.Err())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d records, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i].format != want[i].format || got[i].line != want[i].line ||
+			got[i].rec.Address != want[i].rec.Address ||
+			!bytes.Equal(got[i].rec.Bytes, want[i].rec.Bytes) {
+			t.Errorf("record %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+	if start, ok := m.Start(); !ok || start != 0x100 {
+		t.Errorf("expected start 100, got %X (%v)", start, ok)
+	}
+}
+
+func TestMixedParserErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"S10500000102F7\n:020010000304E8\n",
+			"line 2: invalid checksum: stored E8, computed E7 " +
+				"(address 00000010-00000011)"},
+		{":020010000304E7\nS10500000102F8\n",
+			"line 2: invalid checksum: stored F8, computed F7"},
+		{":020010000304E7\n\nX\n", "line 3: missing record mark"},
+	}
+	for _, tt := range tests {
+		m := NewMixedParser(strings.NewReader(tt.input))
+		for m.Parse() {
+		}
+		if m.Err() == nil || m.Err().Error() != tt.err {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.err, m.Err())
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// Package srec implements a parser and writer for Motorola S-record
+// files, with the same interface as the Parser and Writer of package
+// ihex so that data can be converted between the two formats.
+package srec
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/edmccard/ihex"
+)
+
+// addrLens gives the length of the address field of each record type,
+// or zero for a reserved type.
+var addrLens = [...]int{2, 2, 3, 4, 0, 2, 3, 4, 3, 2}
+
+// A Parser reads data records (types S1, S2, and S3) from an
+// io.Reader, with an interface similar to bufio.Scanner. Errors are
+// reported as an ihex.ParseError.
+type Parser struct {
+	scanner *bufio.Scanner
+	field   [256]byte
+	data    ihex.Record
+	header  []byte
+	start   uint32
+	ended   bool
+	count   int
+	line    int
+	err     error
+}
+
+// NewParser returns a new Parser to read from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{scanner: bufio.NewScanner(r)}
+}
+
+// Parse reads the next data record, which can then be accessed by the
+// Data method. It returns false when there are no more data records,
+// or if an error occurred during parsing. After parsing is finished,
+// the start address from the termination record can be accessed by the
+// Start method; an error, if any, can be accessed by the Err method.
+func (p *Parser) Parse() bool {
+	for p.err == nil {
+		if !p.scanner.Scan() {
+			p.err = p.scanner.Err()
+			if p.err == nil && !p.ended {
+				p.err = p.makeError("missing termination record")
+			}
+			return false
+		}
+		b := p.scanner.Bytes()
+		if len(b) == 0 {
+			p.line++
+			continue
+		}
+		if p.ended {
+			p.err = p.makeError("record after end")
+			return false
+		}
+		p.line++
+		if p.parseLine(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLine parses a line of input, returning true if it was a data
+// record.
+func (p *Parser) parseLine(b []byte) bool {
+	if b[0] != 'S' {
+		p.err = p.makeError("missing record mark")
+		return false
+	}
+	if len(b) < 4 || b[1] < '0' || b[1] > '9' || addrLens[b[1]-'0'] == 0 {
+		p.err = p.makeError("invalid record type")
+		return false
+	}
+	rectyp := b[1] - '0'
+	alen := addrLens[rectyp]
+	if _, err := hex.Decode(p.field[:1], b[2:4]); err != nil {
+		p.err = p.makeError("invalid hex digit")
+		return false
+	}
+	count := int(p.field[0])
+	if count < alen+1 {
+		p.err = p.makeError("invalid record length")
+		return false
+	}
+	text := b[4:]
+	if len(text) < 2*count {
+		p.err = p.makeError(fmt.Sprintf(
+			"record too short: expected %d bytes, found %d",
+			count, len(text)/2))
+		return false
+	}
+	if len(text) > 2*count {
+		p.err = p.makeError("trailing data")
+		return false
+	}
+	field := p.field[1 : 1+count]
+	if _, err := hex.Decode(field, text); err != nil {
+		p.err = p.makeError("invalid hex digit")
+		return false
+	}
+	sum := byte(count)
+	for _, c := range field[:count-1] {
+		sum += c
+	}
+	if stored := field[count-1]; stored != ^sum {
+		p.err = p.makeError(fmt.Sprintf(
+			"invalid checksum: stored %02X, computed %02X", stored, ^sum))
+		return false
+	}
+	var addr uint32
+	for _, c := range field[:alen] {
+		addr = addr<<8 | uint32(c)
+	}
+	data := field[alen : count-1]
+	switch rectyp {
+	case 0:
+		p.header = append(p.header[:0], data...)
+	case 1, 2, 3:
+		p.count++
+		p.data = ihex.Record{Address: addr, Bytes: data}
+		return true
+	case 5, 6:
+		if int(addr) != p.count {
+			p.err = p.makeError(fmt.Sprintf(
+				"record count mismatch: stored %d, counted %d",
+				addr, p.count))
+		}
+	case 7, 8, 9:
+		p.start = addr
+		p.ended = true
+	}
+	return false
+}
+
+// Data returns the last data record read by the Parse method. The
+// underlying data may be overwritten by subsequent calls to Parse.
+func (p *Parser) Data() ihex.Record {
+	return p.data
+}
+
+// Header returns the data from the last header record (type S0) read
+// by the Parser, or nil if there was none.
+func (p *Parser) Header() []byte {
+	return p.header
+}
+
+// Start returns the start address from the termination record, with ok
+// true if the Parser has read it.
+func (p *Parser) Start() (addr uint32, ok bool) {
+	return p.start, p.ended
+}
+
+// LineNumber returns the number of the line read by the last call to
+// Parse, counting from 1.
+func (p *Parser) LineNumber() int {
+	return p.line
+}
+
+// Err returns the first error that was encountered by the Parser.
+func (p *Parser) Err() error {
+	return p.err
+}
+
+func (p *Parser) makeError(msg string) error {
+	return ihex.ParseError{Line: p.line, Msg: msg}
+}
+
+var errClosed = errors.New("write after close")
+
+// A Writer writes records in Motorola S-record format to an io.Writer.
+// It splits data into records of a fixed maximum length and computes
+// checksums. Output is buffered; Close writes the count and termination
+// records and flushes it.
+type Writer struct {
+	w      *bufio.Writer
+	reclen int
+	alen   int
+	count  int
+	start  uint32
+	buf    []byte
+	closed bool
+	err    error
+}
+
+// A WriterOption configures a Writer.
+type WriterOption func(*Writer)
+
+// RecordLength sets the maximum number of data bytes in each data
+// record, which must be from 1 to 250; the default is 16.
+func RecordLength(n int) WriterOption {
+	return func(w *Writer) {
+		w.reclen = n
+	}
+}
+
+// AddressSize sets the number of bytes in the address of each record:
+// 2 for S19 files, 3 for S28 files, or 4 (the default) for S37 files.
+func AddressSize(n int) WriterOption {
+	return func(w *Writer) {
+		w.alen = n
+	}
+}
+
+// NewWriter returns a new Writer that writes to w, configured by any
+// options given.
+func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
+	wr := &Writer{w: bufio.NewWriter(w), reclen: 16, alen: 4}
+	for _, opt := range opts {
+		opt(wr)
+	}
+	if wr.alen < 2 || wr.alen > 4 {
+		wr.err = fmt.Errorf("invalid address size %d", wr.alen)
+	} else if wr.reclen < 1 || wr.reclen > 250 {
+		wr.err = fmt.Errorf("invalid record length %d", wr.reclen)
+	}
+	return wr
+}
+
+// WriteHeader writes a header record (type S0) holding b, which is
+// usually a module name or comment, and should come before any data.
+func (w *Writer) WriteHeader(b []byte) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	if len(b) > 252 {
+		w.err = fmt.Errorf("header of %d bytes is too long", len(b))
+		return w.err
+	}
+	w.record(0, 2, 0, b)
+	return w.err
+}
+
+// WriteData writes data records holding b, starting at addr.
+func (w *Writer) WriteData(addr uint32, b []byte) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	limit := uint64(1) << (8 * w.alen)
+	if uint64(addr)+uint64(len(b)) > limit {
+		w.err = fmt.Errorf("data at %08X-%08X outside of address space",
+			addr, uint64(addr)+uint64(len(b))-1)
+		return w.err
+	}
+	for len(b) > 0 && w.err == nil {
+		n := min(len(b), w.reclen)
+		w.record(byte(w.alen-1), w.alen, addr, b[:n])
+		w.count++
+		addr += uint32(n)
+		b = b[n:]
+	}
+	return w.err
+}
+
+// WriteStart sets the start address written in the termination record,
+// which is otherwise zero.
+func (w *Writer) WriteStart(addr uint32) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	if w.alen < 4 && addr >= 1<<(8*w.alen) {
+		w.err = fmt.Errorf("start address %08X outside of address space",
+			addr)
+		return w.err
+	}
+	w.start = addr
+	return nil
+}
+
+// Close writes a count record, if the number of data records fits in
+// one, and the termination record, then flushes any buffered output. It
+// does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	if w.count < 1<<16 {
+		w.record(5, 2, uint32(w.count), nil)
+	} else if w.count < 1<<24 {
+		w.record(6, 3, uint32(w.count), nil)
+	}
+	w.record(byte(11-w.alen), w.alen, w.start, nil)
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	w.closed = true
+	return w.err
+}
+
+func (w *Writer) check() error {
+	if w.closed {
+		return errClosed
+	}
+	return w.err
+}
+
+// record writes a single record with a computed checksum.
+func (w *Writer) record(rectyp byte, alen int, addr uint32, data []byte) {
+	if w.err != nil {
+		return
+	}
+	count := byte(alen + len(data) + 1)
+	sum := count
+	b := append(w.buf[:0], 'S', '0'+rectyp)
+	b = appendHexByte(b, count)
+	for i := alen - 1; i >= 0; i-- {
+		c := byte(addr >> (8 * i))
+		b = appendHexByte(b, c)
+		sum += c
+	}
+	for _, c := range data {
+		b = appendHexByte(b, c)
+		sum += c
+	}
+	b = appendHexByte(b, ^sum)
+	b = append(b, '\n')
+	w.buf = b
+	_, w.err = w.w.Write(b)
+}
+
+const hexDigits = "0123456789ABCDEF"
+
+func appendHexByte(b []byte, v byte) []byte {
+	return append(b, hexDigits[v>>4], hexDigits[v&0xf])
+}
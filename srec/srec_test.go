@@ -0,0 +1,109 @@
+package srec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/edmccard/ihex"
+)
+
+func TestParser(t *testing.T) {
+	records := `S0060000686472BB
+S10512340102B1
+S104123603B0
+
+S5030002FA
+S9031234B6
+`
+	p := NewParser(strings.NewReader(records))
+	var got []ihex.Record
+	for p.Parse() {
+		d := p.Data()
+		got = append(got, ihex.Record{Address: d.Address, Bytes: bytes.Clone(d.Bytes)})
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	if len(got) != 2 || got[0].Address != 0x1234 ||
+		!bytes.Equal(got[0].Bytes, []byte{1, 2}) ||
+		got[1].Address != 0x1236 || !bytes.Equal(got[1].Bytes, []byte{3}) {
+		t.Errorf("unexpected records %v", got)
+	}
+	if string(p.Header()) != "hdr" {
+		t.Errorf("expected header %q, got %q", "hdr", p.Header())
+	}
+	if start, ok := p.Start(); !ok || start != 0x1234 {
+		t.Errorf("expected start 1234, got %X (%v)", start, ok)
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"S10512340102B2\n", "line 1: invalid checksum: stored B2, computed B1"},
+		{"S40512340102B1\n", "line 1: invalid record type"},
+		{"S105123401\n", "line 1: record too short: expected 5 bytes, found 3"},
+		{"S10512340102B100\n", "line 1: trailing data"},
+		{":00000001FF\n", "line 1: missing record mark"},
+		{"S10512340102B1\n", "line 1: missing termination record"},
+		{"S10512340102B1\nS5030002FA\nS9031234B6\n",
+			"line 2: record count mismatch: stored 2, counted 1"},
+		{"S9031234B6\nS9031234B6\n", "line 1: record after end"},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input))
+		for p.Parse() {
+		}
+		if p.Err() == nil || p.Err().Error() != tt.err {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.err, p.Err())
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b, AddressSize(2), RecordLength(2))
+	w.WriteHeader([]byte("hdr"))
+	w.WriteData(0x1234, []byte{1, 2, 3})
+	w.WriteStart(0x1234)
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `S0060000686472BB
+S10512340102B1
+S104123603B0
+S5030002FA
+S9031234B6
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+
+	w = NewWriter(&b, AddressSize(2))
+	if err := w.WriteData(0xffff, []byte{1, 2}); err == nil {
+		t.Error("expected error for data outside of address space")
+	}
+}
+
+func TestFromIHEX(t *testing.T) {
+	p := ihex.NewParser(strings.NewReader(":01000000AA55\n:00000001FF\n"))
+	var b strings.Builder
+	w := NewWriter(&b)
+	for p.Parse() {
+		d := p.Data()
+		w.WriteData(d.Address, d.Bytes)
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := "S30600000000AA4F\nS5030001FB\nS70500000000FA\n"
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,120 @@
+package ihex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// RecordStats describes the shape of the records in a HEX file.
+type RecordStats struct {
+	Types   map[byte]int   // number of records of each type
+	Lengths map[int]int    // number of data records of each length
+	Windows map[uint32]int // number of data records in each 64K window
+}
+
+// BaseRecords returns the number of extended segment and extended linear
+// address records (types 2 and 4).
+func (s *RecordStats) BaseRecords() int {
+	return s.Types[2] + s.Types[4]
+}
+
+// ReadRecordStats returns statistics for the records read by p. Windows
+// are keyed by the upper 16 bits of their addresses, and a data record
+// is counted in the window of its first byte.
+func ReadRecordStats(p *Parser) (*RecordStats, error) {
+	s := &RecordStats{
+		Types:   make(map[byte]int),
+		Lengths: make(map[int]int),
+		Windows: make(map[uint32]int),
+	}
+	p.onRecord = func(rectyp, reclen byte) {
+		s.Types[rectyp]++
+		if rectyp == 0 {
+			s.Lengths[int(reclen)]++
+			s.Windows[p.Data().Address>>16]++
+		}
+	}
+	for p.Parse() {
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteText writes a summary of s to w, with the counts for each record
+// type, data record length, and window in ascending order.
+func (s *RecordStats) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "record types:")
+	for _, typ := range sortedKeys(s.Types) {
+		fmt.Fprintf(bw, "  %02X %d\n", typ, s.Types[typ])
+	}
+	fmt.Fprintln(bw, "data record lengths:")
+	for _, n := range sortedKeys(s.Lengths) {
+		fmt.Fprintf(bw, "  %3d %d\n", n, s.Lengths[n])
+	}
+	fmt.Fprintln(bw, "data records per 64K window:")
+	for _, win := range sortedKeys(s.Windows) {
+		fmt.Fprintf(bw, "  %08X %d\n", win<<16, s.Windows[win])
+	}
+	return bw.Flush()
+}
+
+func sortedKeys[K int | byte | uint32](m map[K]int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// A Histogram holds the number of times each byte value occurs in the
+// data of an Image.
+type Histogram [256]int
+
+// Histogram returns a Histogram of the data in the Image. Gaps between
+// segments are not counted.
+func (img *Image) Histogram() *Histogram {
+	var h Histogram
+	for _, seg := range img.segs {
+		for _, b := range seg.Bytes {
+			h[b]++
+		}
+	}
+	return &h
+}
+
+// Total returns the number of bytes counted.
+func (h *Histogram) Total() int {
+	n := 0
+	for _, c := range h {
+		n += c
+	}
+	return n
+}
+
+// EraseValue returns the more common of 0xFF and 0x00, the values that
+// erased memory usually reads as, and so the likely erase value of the
+// memory that the data came from. It returns 0xFF if they are equally
+// common.
+func (h *Histogram) EraseValue() byte {
+	if h[0x00] > h[0xff] {
+		return 0x00
+	}
+	return 0xff
+}
+
+// FillRatio returns the fraction of the bytes counted that have value
+// v, or zero if none were counted. A dump in which nearly every byte
+// has the erase value, for example, is likely to be from a failed read.
+func (h *Histogram) FillRatio(v byte) float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	return float64(h[v]) / float64(total)
+}
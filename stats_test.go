@@ -0,0 +1,65 @@
+package ihex
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRecordStats(t *testing.T) {
+	records := `
+:0B0010006164647265737320676170A7
+:020000021200EA
+:0B0010006164647265737320676170A7
+:02FFFF00000000
+:02000004FFFFFC
+:0B0010006164647265737320676170A7
+:00000001FF
+`
+	s, err := ReadRecordStats(ParseString(records))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if s.BaseRecords() != 2 || s.Types[0] != 4 || s.Types[1] != 1 {
+		t.Error("incorrect type counts", s.Types)
+	}
+	var buf bytes.Buffer
+	if err := s.WriteText(&buf); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	expected := `record types:
+  00 4
+  01 1
+  02 1
+  04 1
+data record lengths:
+    2 1
+   11 3
+data records per 64K window:
+  00000000 1
+  00010000 1
+  00020000 1
+  FFFF0000 1
+`
+	if buf.String() != expected {
+		t.Errorf("incorrect summary:\n%s", buf.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var img Image
+	img.WriteAt([]byte{0, 0, 0, 1, 0xff, 0}, 0x100)
+	img.WriteAt([]byte{0, 0xff}, 0x200)
+	h := img.Histogram()
+	if h[0] != 5 || h[1] != 1 || h[0xff] != 2 || h.Total() != 8 {
+		t.Errorf("incorrect histogram %v", h)
+	}
+	if v := h.EraseValue(); v != 0 {
+		t.Errorf("expected erase value 00, got %02X", v)
+	}
+	if r := h.FillRatio(0); r != 0.625 {
+		t.Errorf("expected fill ratio 0.625, got %v", r)
+	}
+	if r := new(Image).Histogram().FillRatio(0xff); r != 0 {
+		t.Errorf("expected fill ratio 0 for empty Image, got %v", r)
+	}
+}
@@ -0,0 +1,55 @@
+package ihex
+
+import "io"
+
+// A Region is a range of addresses that hold data.
+type Region struct {
+	Address uint32
+	Len     int
+}
+
+// A Summary describes the contents of a HEX file.
+type Summary struct {
+	Bytes      int          // number of data bytes in data records
+	MinAddress uint32       // lowest address holding data
+	MaxAddress uint32       // highest address holding data
+	Regions    []Region     // contiguous regions, in address order
+	Types      map[byte]int // number of records of each type
+	EIP        uint32       // start address from a record of type 5
+	HasEIP     bool
+	CS, IP     uint16 // start address from a record of type 3
+	HasCSIP    bool
+}
+
+// Summarize returns a Summary of the Intel HEX file read from r, which
+// is computed as the file is read, holding only the address ranges of
+// its data in memory.
+func Summarize(r io.Reader) (Summary, error) {
+	s := Summary{Types: make(map[byte]int)}
+	p := NewParser(r)
+	p.onRecord = func(rectyp, reclen byte) {
+		s.Types[rectyp]++
+	}
+	var used ranges
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		data := p.Data()
+		s.Bytes += len(data.Bytes)
+		used.add(data)
+	}
+	if err := p.Err(); err != nil {
+		return Summary{}, err
+	}
+	for _, r := range used {
+		s.Regions = append(s.Regions, Region{uint32(r.start), int(r.end - r.start)})
+	}
+	if len(used) > 0 {
+		s.MinAddress = uint32(used[0].start)
+		s.MaxAddress = uint32(used[len(used)-1].end - 1)
+	}
+	s.EIP, s.HasEIP = p.EIP()
+	s.CS, s.IP, s.HasCSIP = p.CSIP()
+	return s, nil
+}
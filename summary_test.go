@@ -0,0 +1,39 @@
+package ihex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	records := `:020000040001F9
+:0100120005E8
+:0100100001EE
+:0100110004EA
+:0100200006D9
+:0400000500000100F6
+:00000001FF
+`
+	s, err := Summarize(strings.NewReader(records))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if s.Bytes != 4 || s.MinAddress != 0x10010 || s.MaxAddress != 0x10020 {
+		t.Errorf("unexpected totals %d, %X-%X", s.Bytes, s.MinAddress, s.MaxAddress)
+	}
+	want := []Region{{0x10010, 3}, {0x10020, 1}}
+	if len(s.Regions) != len(want) {
+		t.Fatalf("expected regions %v, got %v", want, s.Regions)
+	}
+	for i := range want {
+		if s.Regions[i] != want[i] {
+			t.Errorf("expected regions %v, got %v", want, s.Regions)
+		}
+	}
+	if s.Types[0] != 4 || s.Types[1] != 1 || s.Types[4] != 1 || s.Types[5] != 1 {
+		t.Errorf("unexpected record counts %v", s.Types)
+	}
+	if !s.HasEIP || s.EIP != 0x100 || s.HasCSIP {
+		t.Errorf("unexpected start address %X (%v), CSIP %v", s.EIP, s.HasEIP, s.HasCSIP)
+	}
+}
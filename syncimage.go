@@ -0,0 +1,84 @@
+package ihex
+
+import (
+	"bytes"
+	"sync"
+)
+
+// A SyncImage is an Image that is safe for concurrent use by multiple
+// goroutines, so that, for example, a server can serve reads of a
+// shared firmware image while it is patched in the background. The
+// zero value is an empty SyncImage ready to use.
+type SyncImage struct {
+	mu  sync.RWMutex
+	img Image
+}
+
+// ReadAt is like the ReadAt method of Image.
+func (s *SyncImage) ReadAt(b []byte, addr int64) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.img.ReadAt(b, addr)
+}
+
+// WriteAt is like the WriteAt method of Image.
+func (s *SyncImage) WriteAt(b []byte, addr int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.img.WriteAt(b, addr)
+}
+
+// Size is like the Size method of Image.
+func (s *SyncImage) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.img.Size()
+}
+
+// Load is like the Load method of Image. The records are read before
+// the SyncImage is locked, so that readers are only held up while they
+// are added.
+func (s *SyncImage) Load(p *Parser) error {
+	var src Image
+	if err := src.Load(p); err != nil {
+		return err
+	}
+	return s.Merge(&src, LastWins)
+}
+
+// Merge is like the Merge method of Image.
+func (s *SyncImage) Merge(src *Image, policy ConflictPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.img.Merge(src, policy)
+}
+
+// Snapshot returns a copy of the current contents of the SyncImage,
+// which does not share memory with it.
+func (s *SyncImage) Snapshot() *Image {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	img := &Image{start: s.img.start}
+	for _, seg := range s.img.segs {
+		img.segs = append(img.segs, Record{seg.Address, bytes.Clone(seg.Bytes)})
+	}
+	return img
+}
+
+// View calls fn with the Image held by the SyncImage, which fn must not
+// modify, or use once it returns. Other readers may run at the same
+// time, but writers wait until fn returns.
+func (s *SyncImage) View(fn func(img *Image)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(&s.img)
+}
+
+// Update calls fn with the Image held by the SyncImage, which fn may
+// modify but must not use once it returns, and returns the error from
+// fn. No other reader or writer runs until fn returns.
+func (s *SyncImage) Update(fn func(img *Image) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&s.img)
+}
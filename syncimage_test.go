@@ -0,0 +1,51 @@
+package ihex
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestSyncImage(t *testing.T) {
+	var s SyncImage
+	if err := s.Load(ParseString(":0400000001020304F2\n:00000001FF\n")); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	snap := s.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.WriteAt([]byte{byte(i)}, int64(0x100+i))
+		}()
+		go func() {
+			defer wg.Done()
+			var b [4]byte
+			if _, err := s.ReadAt(b[:], 0); err != nil || b != [4]byte{1, 2, 3, 4} {
+				t.Error("incorrect read", b, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Size() != 12 {
+		t.Errorf("expected size 12, got %d", s.Size())
+	}
+	s.View(func(img *Image) {
+		checkRecords(t, img.Segments(), []Record{
+			{0, []byte{1, 2, 3, 4}},
+			{0x100, []byte{0, 1, 2, 3, 4, 5, 6, 7}},
+		})
+	})
+	err := s.Update(func(img *Image) error {
+		return img.Merge(snap, ConflictError)
+	})
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+	if snap.Size() != 4 || !bytes.Equal(snap.Segments()[0].Bytes, []byte{1, 2, 3, 4}) {
+		t.Error("snapshot changed", snap.Segments())
+	}
+}
@@ -0,0 +1,125 @@
+package ihex
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// A Trailer describes a whole-file checksum that some tools add to an
+// Intel HEX file on a line of its own, as a comment or as a
+// vendor-specific record.
+type Trailer struct {
+	// New returns a hash.Hash for the checksum, which is computed over
+	// the data bytes of every data record, in the order they appear.
+	New func() hash.Hash
+
+	// Match reports whether line, without its terminator, is the
+	// trailer, and if so returns the checksum that it holds.
+	Match func(line []byte) (sum []byte, ok bool)
+
+	// Format returns the trailer line, without its terminator, holding
+	// sum. It is only needed for writing.
+	Format func(sum []byte) []byte
+}
+
+// CommentTrailer returns a Trailer for a line made up of prefix
+// followed by the checksum in hexadecimal, such as "; CRC32: 1A2B3C4D"
+// for the prefix "; CRC32: ".
+func CommentTrailer(prefix string, newHash func() hash.Hash) Trailer {
+	return Trailer{
+		New: newHash,
+		Match: func(line []byte) ([]byte, bool) {
+			text, ok := bytes.CutPrefix(line, []byte(prefix))
+			if !ok {
+				return nil, false
+			}
+			sum, err := hex.DecodeString(string(bytes.TrimSpace(text)))
+			return sum, err == nil
+		},
+		Format: func(sum []byte) []byte {
+			return fmt.Appendf([]byte(prefix), "%X", sum)
+		},
+	}
+}
+
+// VerifyTrailer makes the Parser check the whole-file checksum
+// described by t, reporting an error at the end of the input if the
+// trailer is missing or does not match the data. The trailer may come
+// before or after the end record; it is not passed to the
+// NonRecordLines policy.
+func VerifyTrailer(t Trailer) Option {
+	return func(p *Parser) {
+		p.trailer = t.Match
+		p.hash = t.New()
+	}
+}
+
+// EmitTrailer makes the Writer write the trailer described by t after
+// the end record, holding the checksum of the data it was given, so
+// that the output can be checked with VerifyTrailer. The Format field
+// of t must be set.
+func EmitTrailer(t Trailer) WriterOption {
+	return func(w *Writer) {
+		w.trailer = t.Format
+		w.hash = t.New()
+	}
+}
+
+// writeTrailer writes the trailer line, if there is one.
+func (w *Writer) writeTrailer() {
+	if w.trailer == nil || w.err != nil {
+		return
+	}
+	b := append(w.trailer(w.hash.Sum(nil)), w.eol...)
+	_, w.err = w.w.Write(b)
+}
+
+// readTrailer reports whether b, the text of the given line, is the
+// trailer, remembering the checksum it holds.
+func (p *Parser) readTrailer(b []byte, line int) bool {
+	if p.trailer == nil {
+		return false
+	}
+	sum, ok := p.trailer(b)
+	if !ok {
+		return false
+	}
+	if p.trailerLine != 0 {
+		p.err = ParseError{Line: line, Msg: "duplicate trailer"}
+		return true
+	}
+	p.trailerSum = sum
+	p.trailerLine = line
+	return true
+}
+
+// checkTrailer compares the trailer checksum to the data that was read,
+// looking for the trailer in any content that followed the end record
+// if it has not been seen.
+func (p *Parser) checkTrailer() {
+	if p.trailer == nil {
+		return
+	}
+	n := p.line
+	for line := range bytes.Lines(p.trailing) {
+		if p.trailerLine != 0 {
+			break
+		}
+		n++
+		p.readTrailer(bytes.TrimRight(line, "\r\n"), n)
+	}
+	if p.err != nil {
+		return
+	}
+	if p.trailerLine == 0 {
+		p.err = p.makeError("missing trailer")
+		return
+	}
+	if sum := p.hash.Sum(nil); !bytes.Equal(sum, p.trailerSum) {
+		p.err = ParseError{Line: p.trailerLine, Msg: fmt.Sprintf(
+			"trailer checksum mismatch: stored %X, computed %X",
+			p.trailerSum, sum)}
+	}
+}
@@ -0,0 +1,93 @@
+package ihex
+
+import (
+	"hash"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+var crcTrailer = CommentTrailer("; CRC32: ", func() hash.Hash {
+	return crc32.NewIEEE()
+})
+
+func TestVerifyTrailer(t *testing.T) {
+	const records = `:050000000102030405EC
+:02001000AABB89
+`
+	tests := []struct {
+		name  string
+		input string
+		opts  []Option
+		err   string
+	}{
+		{"after end", records + ":00000001FF\n; CRC32: B08164CA\n", nil, ""},
+		{"before end", records + "; CRC32: b08164ca\n:00000001FF\n", nil, ""},
+		{"stop at end", records + ":00000001FF\n\n; CRC32: B08164CA\n",
+			[]Option{StopAtEnd()}, ""},
+		{"mismatch", records + ":00000001FF\n; CRC32: B08164CB\n", nil,
+			"line 4: trailer checksum mismatch: stored B08164CB, computed B08164CA"},
+		{"missing", records + ":00000001FF\n", nil, "line 3: missing trailer"},
+		{"duplicate", records + "; CRC32: B08164CA\n:00000001FF\n; CRC32: B08164CA\n",
+			nil, "line 5: duplicate trailer"},
+		{"stop at end mismatch", records + ":00000001FF\n\n; CRC32: 00000000\n",
+			[]Option{StopAtEnd()},
+			"line 5: trailer checksum mismatch: stored 00000000, computed B08164CA"},
+	}
+	for _, tt := range tests {
+		p := ParseString(tt.input, append(tt.opts, VerifyTrailer(crcTrailer))...)
+		n := 0
+		for p.Parse() {
+			n++
+		}
+		err := ""
+		if p.Err() != nil {
+			err = p.Err().Error()
+		}
+		if err != tt.err {
+			t.Errorf("%s: expected error %q, got %q", tt.name, tt.err, err)
+		}
+		if tt.err == "" && n != 2 {
+			t.Errorf("%s: expected 2 records, got %d", tt.name, n)
+		}
+	}
+}
+
+func TestVerifyTrailerTee(t *testing.T) {
+	input := ":0100000001FE\n; CRC32: A505DF1B\n:00000001FF\n"
+	p := ParseString(input, Tee(), VerifyTrailer(crcTrailer))
+	lines := ""
+	for p.Parse() {
+		lines += string(p.Line())
+	}
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	if lines != input {
+		t.Errorf("expected lines %q, got %q", input, lines)
+	}
+}
+
+func TestEmitTrailer(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b, RecordLength(5), EmitTrailer(crcTrailer))
+	w.WriteData(0, []byte{1, 2, 3, 4, 5})
+	w.WriteData(0x10, []byte{0xaa, 0xbb})
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `:050000000102030405EC
+:02001000AABB89
+:00000001FF
+; CRC32: B08164CA
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+	p := ParseString(b.String(), VerifyTrailer(crcTrailer))
+	for p.Parse() {
+	}
+	if p.Err() != nil {
+		t.Error("unexpected error", p.Err())
+	}
+}
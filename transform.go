@@ -0,0 +1,352 @@
+package ihex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// A Transform processes a stream of data records, returning zero or
+// more records for each record that it is given. The Bytes of the
+// records passed to Next may be overwritten once Next returns, so a
+// Transform that keeps them must make a copy.
+type Transform interface {
+	Next(Record) ([]Record, error)
+}
+
+// A TransformFunc is an ordinary function used as a Transform.
+type TransformFunc func(Record) ([]Record, error)
+
+// Next returns f(r).
+func (f TransformFunc) Next(r Record) ([]Record, error) {
+	return f(r)
+}
+
+// Chain returns a Transform that passes records through each of ts in
+// turn.
+func Chain(ts ...Transform) Transform {
+	return TransformFunc(func(r Record) ([]Record, error) {
+		recs := []Record{r}
+		for _, t := range ts {
+			var out []Record
+			for _, rec := range recs {
+				next, err := t.Next(rec)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, next...)
+			}
+			recs = out
+		}
+		return recs, nil
+	})
+}
+
+// Apply passes each data record read by p through t, calling fn for
+// each record that results. It returns the first error from p, t, or
+// fn.
+func Apply(p *Parser, t Transform, fn func(Record) error) error {
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		recs, err := t.Next(p.Data())
+		if err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return p.Err()
+}
+
+// Transform passes each segment of the Image through t, in order of
+// address, and replaces the contents of the Image with the records that
+// result, with data from later records replacing any earlier data at
+// the same address. It can be used to apply the same transforms to an
+// Image as to a stream of records, such as Scatter or Fill. The Image is
+// unchanged if there is an error.
+func (img *Image) Transform(t Transform) error {
+	var segs spans
+	for _, seg := range img.segs {
+		recs, err := t.Next(seg)
+		if err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			segs.add(rec)
+		}
+	}
+	img.segs = segs
+	return nil
+}
+
+// Offset returns a Transform that adds delta to the address of each
+// record. It is an error for a record to be moved outside of the 32-bit
+// address space.
+func Offset(delta int64) Transform {
+	return TransformFunc(func(r Record) ([]Record, error) {
+		addr := int64(r.Address) + delta
+		if addr < 0 || addr+int64(len(r.Bytes)) > 1<<32 {
+			return nil, fmt.Errorf("offset %d moves record at %08X "+
+				"outside of address space", delta, r.Address)
+		}
+		r.Address = uint32(addr)
+		return []Record{r}, nil
+	})
+}
+
+// Crop returns a Transform that keeps only the bytes with addresses in
+// the range [start, end).
+func Crop(start, end uint32) Transform {
+	return TransformFunc(func(r Record) ([]Record, error) {
+		lo := max(uint64(r.Address), uint64(start))
+		hi := min(uint64(r.Address)+uint64(len(r.Bytes)), uint64(end))
+		if lo >= hi {
+			return nil, nil
+		}
+		r.Bytes = r.Bytes[lo-uint64(r.Address) : hi-uint64(r.Address)]
+		r.Address = uint32(lo)
+		return []Record{r}, nil
+	})
+}
+
+// Fill returns a Transform that fills the gap between the end of each
+// record and the start of the following one with value. Only records
+// that follow the previous one in address order are preceded by a fill
+// record; since gaps are filled completely, Crop should be used first
+// to bound the size of the fill.
+func Fill(value byte) Transform {
+	var next uint64
+	started := false
+	return TransformFunc(func(r Record) ([]Record, error) {
+		var recs []Record
+		if started && uint64(r.Address) > next {
+			gap := uint64(r.Address) - next
+			recs = append(recs, Record{
+				Address: uint32(next),
+				Bytes:   bytes.Repeat([]byte{value}, int(gap)),
+			})
+		}
+		started = true
+		next = max(next, uint64(r.Address)+uint64(len(r.Bytes)))
+		return append(recs, r), nil
+	})
+}
+
+// Split returns a Transform that splits records so that none of them
+// crosses an address that is a multiple of n. If n is zero, the
+// Transform returns an error for every record.
+func Split(n uint32) Transform {
+	if n == 0 {
+		err := errors.New("split size must be greater than zero")
+		return TransformFunc(func(Record) ([]Record, error) {
+			return nil, err
+		})
+	}
+	return TransformFunc(func(r Record) ([]Record, error) {
+		var recs []Record
+		for len(r.Bytes) > 0 {
+			size := uint64(n) - uint64(r.Address%n)
+			size = min(size, uint64(len(r.Bytes)))
+			recs = append(recs, Record{r.Address, r.Bytes[:size]})
+			r.Address += uint32(size)
+			r.Bytes = r.Bytes[size:]
+		}
+		return recs, nil
+	})
+}
+
+// Dedupe returns a Transform that drops bytes written to an address
+// that an earlier record already wrote with the same value. It is an
+// error for a record to write a different value to such an address.
+func Dedupe() Transform {
+	var seen spans
+	return TransformFunc(func(r Record) ([]Record, error) {
+		recs, err := seen.dedupe(r, true)
+		if err != nil {
+			return nil, err
+		}
+		seen.add(r)
+		return recs, nil
+	})
+}
+
+// spans holds copies of non-overlapping records, sorted by address.
+type spans []Record
+
+// find returns the index of the first span that ends after addr.
+func (s spans) find(addr uint64) int {
+	return sort.Search(len(s), func(i int) bool {
+		return uint64(s[i].Address)+uint64(len(s[i].Bytes)) > addr
+	})
+}
+
+// dedupe returns the parts of r not already held in s, or an error if
+// r conflicts with the data in s and check is true.
+func (s spans) dedupe(r Record, check bool) ([]Record, error) {
+	var recs []Record
+	base := uint64(r.Address)
+	addr, end := base, base+uint64(len(r.Bytes))
+	for i := s.find(addr); addr < end; i++ {
+		if i == len(s) || uint64(s[i].Address) >= end {
+			recs = append(recs, Record{uint32(addr), r.Bytes[addr-base:]})
+			break
+		}
+		start := uint64(s[i].Address)
+		if start > addr {
+			recs = append(recs,
+				Record{uint32(addr), r.Bytes[addr-base : start-base]})
+			addr = start
+		}
+		stop := min(end, start+uint64(len(s[i].Bytes)))
+		have := s[i].Bytes[addr-start : stop-start]
+		if check {
+			for i, b := range r.Bytes[addr-base : stop-base] {
+				if b != have[i] {
+					return nil, fmt.Errorf("conflicting data at %08X",
+						uint32(addr)+uint32(i))
+				}
+			}
+		}
+		addr = stop
+	}
+	return recs, nil
+}
+
+// add merges a copy of r into s, which must not conflict with it.
+func (s *spans) add(r Record) {
+	if len(r.Bytes) == 0 {
+		return
+	}
+	addr := uint64(r.Address)
+	end := addr + uint64(len(r.Bytes))
+	// merge with any spans that overlap or touch r
+	i := s.find(addr)
+	if i > 0 {
+		prev := &(*s)[i-1]
+		if uint64(prev.Address)+uint64(len(prev.Bytes)) == addr {
+			if i == len(*s) || uint64((*s)[i].Address) > end {
+				// r extends prev, as when records are added in order
+				prev.Bytes = append(prev.Bytes, r.Bytes...)
+				return
+			}
+			i--
+		}
+	}
+	j := i
+	for j < len(*s) && uint64((*s)[j].Address) <= end {
+		j++
+	}
+	if i < j {
+		addr = min(addr, uint64((*s)[i].Address))
+		last := (*s)[j-1]
+		end = max(end, uint64(last.Address)+uint64(len(last.Bytes)))
+	}
+	merged := make([]byte, end-addr)
+	for _, span := range (*s)[i:j] {
+		copy(merged[uint64(span.Address)-addr:], span.Bytes)
+	}
+	copy(merged[uint64(r.Address)-addr:], r.Bytes)
+	rec := Record{uint32(addr), merged}
+	*s = append((*s)[:i], append([]Record{rec}, (*s)[j:]...)...)
+}
+
+// Filter returns a Transform that drops each record for which pred
+// returns false, so that a predicate written for FilterRecords can also
+// be used in a pipeline. The RawRecord passed to pred has type 0, the
+// Address and Data of the record, the low 16 bits of the address as its
+// Offset, and a Checksum of zero.
+func Filter(pred func(RawRecord) bool) Transform {
+	return TransformFunc(func(r Record) ([]Record, error) {
+		raw := RawRecord{
+			Offset:  uint16(r.Address),
+			Address: r.Address,
+			Data:    r.Bytes,
+		}
+		if !pred(raw) {
+			return nil, nil
+		}
+		return []Record{r}, nil
+	})
+}
+
+// A Mapping is an entry in a scatter table, which moves the bytes with
+// addresses in the range [Start, End) so that Start is moved to Base.
+type Mapping struct {
+	Start, End uint32
+	Base       uint32
+}
+
+// Scatter returns a Transform that applies a scatter table to each
+// record. A byte within the range of more than one mapping is copied to
+// the destination of each, in table order, so that a region can be
+// aliased at several addresses; a byte not within the range of any
+// mapping keeps its address. It is an error for a mapping to move a
+// byte outside of the 32-bit address space.
+func Scatter(table []Mapping) Transform {
+	return TransformFunc(func(r Record) ([]Record, error) {
+		base := uint64(r.Address)
+		end := base + uint64(len(r.Bytes))
+		// split the record wherever a mapping starts or ends
+		cuts := []uint64{base, end}
+		for _, m := range table {
+			for _, c := range []uint64{uint64(m.Start), uint64(m.End)} {
+				if c > base && c < end {
+					cuts = append(cuts, c)
+				}
+			}
+		}
+		sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+		var recs []Record
+		for i := 1; i < len(cuts); i++ {
+			lo, hi := cuts[i-1], cuts[i]
+			if lo == hi {
+				continue
+			}
+			b := r.Bytes[lo-base : hi-base]
+			mapped := false
+			for _, m := range table {
+				if lo < uint64(m.Start) || hi > uint64(m.End) {
+					continue
+				}
+				dst := uint64(m.Base) + lo - uint64(m.Start)
+				if dst+uint64(len(b)) > 1<<32 {
+					return nil, fmt.Errorf("mapping %08X-%08X moves "+
+						"data at %08X outside of address space",
+						m.Start, m.End, uint32(lo))
+				}
+				recs = append(recs, Record{uint32(dst), b})
+				mapped = true
+			}
+			if !mapped {
+				recs = append(recs, Record{uint32(lo), b})
+			}
+		}
+		return recs, nil
+	})
+}
+
+// Exclude returns a Transform that drops the bytes with addresses in
+// the range [start, end).
+func Exclude(start, end uint32) Transform {
+	return TransformFunc(func(r Record) ([]Record, error) {
+		lo := uint64(r.Address)
+		hi := lo + uint64(len(r.Bytes))
+		if hi <= uint64(start) || lo >= uint64(end) || start >= end {
+			return []Record{r}, nil
+		}
+		var recs []Record
+		if lo < uint64(start) {
+			recs = append(recs, Record{r.Address, r.Bytes[:uint64(start)-lo]})
+		}
+		if hi > uint64(end) {
+			recs = append(recs, Record{end, r.Bytes[uint64(end)-lo:]})
+		}
+		return recs, nil
+	})
+}
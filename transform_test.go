@@ -0,0 +1,185 @@
+package ihex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func runTransform(t *testing.T, tr Transform, recs []Record) []Record {
+	t.Helper()
+	var out []Record
+	for _, r := range recs {
+		next, err := tr.Next(r)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		out = append(out, next...)
+	}
+	return out
+}
+
+func checkRecords(t *testing.T, got, want []Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d records, got %d: %v", len(want), len(got), got)
+	}
+	for i := range got {
+		if got[i].Address != want[i].Address ||
+			!bytes.Equal(got[i].Bytes, want[i].Bytes) {
+			t.Errorf("record %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestOffsetCrop(t *testing.T) {
+	recs := []Record{
+		{0x8000, []byte{1, 2, 3, 4}},
+		{0x8010, []byte{5, 6, 7, 8}},
+	}
+	tr := Chain(Crop(0x8002, 0x8012), Offset(-0x8000))
+	checkRecords(t, runTransform(t, tr, recs), []Record{
+		{0x02, []byte{3, 4}},
+		{0x10, []byte{5, 6}},
+	})
+
+	if _, err := Offset(-0x8001).Next(recs[0]); err == nil {
+		t.Error("missed offset below zero")
+	}
+	if _, err := Offset(0xffff8000).Next(recs[0]); err == nil {
+		t.Error("missed offset past 4GiB")
+	}
+}
+
+func TestFillSplit(t *testing.T) {
+	recs := []Record{
+		{0x0e, []byte{1, 2, 3}},
+		{0x14, []byte{4}},
+		{0x00, []byte{5}},
+	}
+	checkRecords(t, runTransform(t, Fill(0xff), recs), []Record{
+		{0x0e, []byte{1, 2, 3}},
+		{0x11, []byte{0xff, 0xff, 0xff}},
+		{0x14, []byte{4}},
+		{0x00, []byte{5}},
+	})
+	checkRecords(t, runTransform(t, Split(4), recs), []Record{
+		{0x0e, []byte{1, 2}},
+		{0x10, []byte{3}},
+		{0x14, []byte{4}},
+		{0x00, []byte{5}},
+	})
+	if _, err := Split(0).Next(recs[0]); err == nil {
+		t.Error("expected error for split size of zero")
+	}
+}
+
+func TestImageTransform(t *testing.T) {
+	img := &Image{}
+	img.WriteAt([]byte{1, 2, 3, 4}, 0x10)
+	img.WriteAt([]byte{5, 6}, 0x20)
+	img.SetStartLinear(0x10)
+	if err := img.Transform(Scatter([]Mapping{{0x10, 0x12, 0x30}})); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, img.Segments(), []Record{
+		{0x12, []byte{3, 4}},
+		{0x20, []byte{5, 6}},
+		{0x30, []byte{1, 2}},
+	})
+	if err := img.Transform(Fill(0)); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := []byte{3, 4}
+	want = append(want, make([]byte, 12)...)
+	want = append(want, 5, 6)
+	want = append(want, make([]byte, 14)...)
+	want = append(want, 1, 2)
+	checkRecords(t, img.Segments(), []Record{{0x12, want}})
+	if eip, ok := img.EIP(); !ok || eip != 0x10 {
+		t.Errorf("expected start address to be kept, got %08X %v", eip, ok)
+	}
+
+	if err := img.Transform(Split(0)); err == nil {
+		t.Error("expected error for split size of zero")
+	}
+	if n := img.Size(); n != 0x20 {
+		t.Errorf("expected Image to be unchanged, got size %d", n)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	tr := Dedupe()
+	recs := []Record{
+		{0x10, []byte{1, 2, 3, 4}},
+		{0x20, []byte{9}},
+		{0x0e, []byte{7, 7, 1, 2, 3, 4, 5, 6}},
+		{0x16, []byte{6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 9, 8}},
+	}
+	checkRecords(t, runTransform(t, tr, recs), []Record{
+		{0x10, []byte{1, 2, 3, 4}},
+		{0x20, []byte{9}},
+		{0x0e, []byte{7, 7}},
+		{0x14, []byte{5, 6}},
+		{0x16, []byte{6, 6, 6, 6, 6, 6, 6, 6, 6, 6}},
+		{0x21, []byte{8}},
+	})
+	if _, err := tr.Next(Record{0x12, []byte{3, 0}}); err == nil {
+		t.Error("missed conflicting data")
+	}
+}
+
+func TestApply(t *testing.T) {
+	records := `
+:0B0010006164647265737320676170A7
+:00000001FF
+`
+	p := NewParser(strings.NewReader(records))
+	var out []Record
+	err := Apply(p, Chain(Split(8), Offset(0x100)), func(r Record) error {
+		out = append(out, Record{r.Address, append([]byte(nil), r.Bytes...)})
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, out, []Record{
+		{0x110, []byte("address ")},
+		{0x118, []byte("gap")},
+	})
+}
+
+func TestFilter(t *testing.T) {
+	recs := []Record{
+		{0x10010, []byte{1, 2}},
+		{0x20, []byte{3}},
+	}
+	tr := Filter(func(r RawRecord) bool {
+		return r.Type == 0 && r.Offset == 0x10 && len(r.Data) == 2
+	})
+	checkRecords(t, runTransform(t, tr, recs), recs[:1])
+}
+
+func TestScatter(t *testing.T) {
+	recs := []Record{
+		{0x0ffe, []byte{1, 2, 3, 4, 5, 6}},
+		{0x2000, []byte{7, 8}},
+	}
+	tr := Scatter([]Mapping{
+		{0x1000, 0x1002, 0x1000},     // keep in place...
+		{0x1000, 0x1002, 0x80001000}, // ...with an alias
+		{0x1002, 0x1004, 0x4000},     // overlay
+	})
+	checkRecords(t, runTransform(t, tr, recs), []Record{
+		{0x0ffe, []byte{1, 2}},
+		{0x1000, []byte{3, 4}},
+		{0x80001000, []byte{3, 4}},
+		{0x4000, []byte{5, 6}},
+		{0x2000, []byte{7, 8}},
+	})
+
+	tr = Scatter([]Mapping{{0x2000, 0x2002, 0xffffffff}})
+	if _, err := tr.Next(recs[1]); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,140 @@
+package ihex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// A Usage describes how a block of addresses is used.
+type Usage byte
+
+const (
+	Hole     Usage = iota // no data in the block
+	FillOnly              // data, but only the fill value
+	Used                  // data other than the fill value
+)
+
+// usageChars are the characters used for each Usage by WriteText.
+var usageChars = [...]byte{Hole: '.', FillOnly: '-', Used: '#'}
+
+// usageColors are the colors used for each Usage by WritePNG.
+var usageColors = color.Palette{
+	Hole:     color.Gray{0xff},
+	FillOnly: color.Gray{0xb0},
+	Used:     color.RGBA{0x20, 0x50, 0xa0, 0xff},
+}
+
+// A UsageMap holds the Usage of a sequence of blocks of the address
+// space.
+type UsageMap struct {
+	Start     uint32 // address of the first block
+	BlockSize uint32
+	Blocks    []Usage
+}
+
+// ReadUsageMap returns a UsageMap for the data records read by p, in
+// blocks of blockSize bytes aligned to a multiple of blockSize. Data
+// bytes equal to fill are considered filler. The map covers the blocks
+// from the lowest to the highest address with data. It is an error for
+// blockSize to be zero.
+func ReadUsageMap(p *Parser, blockSize uint32, fill byte) (*UsageMap,
+	error) {
+	if blockSize == 0 {
+		return nil, errors.New("block size must be greater than zero")
+	}
+	blocks := make(map[uint32]Usage)
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		data := p.Data()
+		addr := uint64(data.Address)
+		b := data.Bytes
+		// update each block that the record covers once
+		for len(b) > 0 {
+			block := uint32(addr / uint64(blockSize))
+			n := min(uint64(len(b)), (uint64(block)+1)*uint64(blockSize)-addr)
+			if !allEqual(b[:n], fill) {
+				blocks[block] = Used
+			} else if blocks[block] == Hole {
+				blocks[block] = FillOnly
+			}
+			addr += n
+			b = b[n:]
+		}
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	m := &UsageMap{BlockSize: blockSize}
+	if len(blocks) == 0 {
+		return m, nil
+	}
+	first, last := ^uint32(0), uint32(0)
+	for block := range blocks {
+		first, last = min(first, block), max(last, block)
+	}
+	m.Start = first * blockSize
+	m.Blocks = make([]Usage, last-first+1)
+	for block, usage := range blocks {
+		m.Blocks[block-first] = usage
+	}
+	return m, nil
+}
+
+// allEqual reports whether every byte of b is equal to v.
+func allEqual(b []byte, v byte) bool {
+	for _, c := range b {
+		if c != v {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteText writes m to w as lines of text, each beginning with the
+// address of its first block and showing perLine blocks as '#' for used
+// data, '-' for filler, and '.' for holes. It is an error for perLine
+// to be less than one.
+func (m *UsageMap) WriteText(w io.Writer, perLine int) error {
+	if perLine < 1 {
+		return errors.New("blocks per line must be greater than zero")
+	}
+	bw := bufio.NewWriter(w)
+	line := make([]byte, 0, perLine)
+	for i := 0; i < len(m.Blocks); i += perLine {
+		line = line[:0]
+		for _, usage := range m.Blocks[i:min(i+perLine, len(m.Blocks))] {
+			line = append(line, usageChars[usage])
+		}
+		addr := uint64(m.Start) + uint64(i)*uint64(m.BlockSize)
+		fmt.Fprintf(bw, "%08X %s\n", addr, line)
+	}
+	return bw.Flush()
+}
+
+// WritePNG writes m to w as a PNG image, with perLine blocks on each row
+// and each block drawn as a square of scale pixels. It is an error for
+// perLine or scale to be less than one.
+func (m *UsageMap) WritePNG(w io.Writer, perLine, scale int) error {
+	if perLine < 1 || scale < 1 {
+		return errors.New("blocks per line and scale must be greater than zero")
+	}
+	rows := (len(m.Blocks) + perLine - 1) / perLine
+	img := image.NewPaletted(
+		image.Rect(0, 0, perLine*scale, max(rows, 1)*scale), usageColors)
+	for i, usage := range m.Blocks {
+		x, y := (i%perLine)*scale, (i/perLine)*scale
+		for dy := 0; dy < scale; dy++ {
+			for dx := 0; dx < scale; dx++ {
+				img.SetColorIndex(x+dx, y+dy, uint8(usage))
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
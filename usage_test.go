@@ -0,0 +1,87 @@
+package ihex
+
+import (
+	"bytes"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+func TestUsageMap(t *testing.T) {
+	records := `
+:0400000001FFFFFFFE
+:04001000FFFFFFFFF0
+:0100400000BF
+:00000001FF
+`
+	m, err := ReadUsageMap(ParseString(records), 16, 0xff)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var buf bytes.Buffer
+	if err := m.WriteText(&buf, 4); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if buf.String() != "00000000 #-..\n00000040 #\n" {
+		t.Errorf("incorrect map:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := m.WritePNG(&buf, 4, 8); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if b := img.Bounds(); b.Dx() != 32 || b.Dy() != 16 {
+		t.Error("incorrect image size", b)
+	}
+	r, g, b, _ := img.At(9, 1).RGBA()
+	wr, wg, wb, _ := usageColors[FillOnly].RGBA()
+	if r != wr || g != wg || b != wb {
+		t.Error("incorrect color", img.At(9, 1))
+	}
+
+	m, err = ReadUsageMap(ParseString(":00000001FF"), 16, 0xff)
+	if err != nil || len(m.Blocks) != 0 {
+		t.Error("unexpected blocks or error", m.Blocks, err)
+	}
+
+	if _, err := ReadUsageMap(ParseString(records), 0, 0xff); err == nil {
+		t.Error("expected error for block size of zero")
+	}
+	if err := m.WriteText(&buf, 0); err == nil {
+		t.Error("expected error for zero blocks per line")
+	}
+	if err := m.WritePNG(&buf, 4, 0); err == nil {
+		t.Error("expected error for scale of zero")
+	}
+	if err := m.WritePNG(&buf, -1, 8); err == nil {
+		t.Error("expected error for negative blocks per line")
+	}
+}
+
+func TestUsageMapSpans(t *testing.T) {
+	// a record spanning blocks, at the top of the address space
+	records := `:02000004FFFFFC
+:0AFFF600FFFFFFFFFF01FFFFFFFF09
+:00000001FF
+`
+	m, err := ReadUsageMap(ParseString(records), 4, 0xff)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := []Usage{FillOnly, Used, FillOnly}
+	if m.Start != 0xfffffff4 || !slices.Equal(m.Blocks, want) {
+		t.Errorf("expected %v at FFFFFFF4, got %v at %X", want, m.Blocks, m.Start)
+	}
+	m, err = ReadUsageMap(ParseString(":02000004FFFFFC\n:02FFFE00FF0101\n"+
+		":00000001FF\n"), 1, 0xff)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if m.Start != 0xfffffffe || !slices.Equal(m.Blocks, []Usage{FillOnly, Used}) {
+		t.Errorf("incorrect blocks %v at %X", m.Blocks, m.Start)
+	}
+}
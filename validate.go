@@ -0,0 +1,130 @@
+package ihex
+
+import (
+	"bufio"
+	"io"
+)
+
+// A ValidatingWriter checks Intel HEX text as it is written, passing
+// each line on to an underlying writer once the line has been found to
+// be valid. It stops at the first invalid line, which is not passed on.
+type ValidatingWriter struct {
+	w    io.Writer
+	p    *Parser
+	feed *feedScanner
+	buf  []byte
+	err  error
+}
+
+// NewValidatingWriter returns a new ValidatingWriter that writes to w,
+// validating its input with a Parser configured by any options given.
+// The TranscodeUTF16 option has no effect.
+func NewValidatingWriter(w io.Writer, opts ...Option) *ValidatingWriter {
+	p := newParser(opts)
+	p.tee = true
+	feed := &feedScanner{p: p}
+	p.scanner = feed
+	return &ValidatingWriter{w: w, p: p, feed: feed}
+}
+
+// Write validates each complete line in b, writing it to the
+// underlying writer if it is valid. An incomplete line at the end of b
+// is held until the rest of it is written. Lines end as they do for a
+// Parser. Write returns the first error from validation or from the
+// underlying writer; a validation error is a ParseError with the
+// position of the problem.
+func (v *ValidatingWriter) Write(b []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	held := len(v.buf)
+	v.buf = append(v.buf, b...)
+	if done, err := v.writeLines(false); err != nil {
+		return max(done-held, 0), err
+	}
+	return len(b), nil
+}
+
+// Close validates and writes any incomplete final line, and checks that
+// the input was complete. It does not close the underlying writer.
+func (v *ValidatingWriter) Close() error {
+	if v.err != nil {
+		return v.err
+	}
+	if _, err := v.writeLines(true); err != nil {
+		return err
+	}
+	v.p.Parse()
+	v.err = v.p.Err()
+	return v.err
+}
+
+// writeLines validates and writes each complete line held in v.buf,
+// keeping any incomplete line, and returns the number of bytes of v.buf
+// that were written.
+func (v *ValidatingWriter) writeLines(atEOF bool) (int, error) {
+	done := 0
+	for done < len(v.buf) {
+		advance, token, _ := scanLines(v.buf[done:], atEOF)
+		if advance == 0 {
+			break
+		}
+		if err := v.writeLine(v.buf[done:done+advance], token); err != nil {
+			return done, err
+		}
+		done += advance
+	}
+	v.buf = v.buf[:copy(v.buf, v.buf[done:])]
+	// allow the same line length as a Parser reading from an io.Reader
+	limit := bufio.MaxScanTokenSize
+	if v.p.maxLine > 0 {
+		limit = v.p.maxLine + 2
+	}
+	if len(v.buf) > limit {
+		v.err = ParseError{Line: v.p.line + 1, Msg: "line too long"}
+		return done, v.err
+	}
+	return done, nil
+}
+
+// writeLine validates a line, given with and without its terminator,
+// and writes it if it is valid.
+func (v *ValidatingWriter) writeLine(raw, line []byte) error {
+	v.feed.raw, v.feed.line = raw, line
+	for v.feed.raw != nil || v.p.wrap != nil {
+		if !v.p.Parse() {
+			break
+		}
+	}
+	if v.err = v.p.Err(); v.err != nil {
+		return v.err
+	}
+	_, v.err = v.w.Write(raw)
+	return v.err
+}
+
+// A feedScanner is a lineScanner that is given one line at a time. Its
+// Scan method returns false when it has no line, so the Parser must
+// only be called for more input once all input has been given.
+type feedScanner struct {
+	p    *Parser
+	raw  []byte // the line, with its terminator
+	line []byte
+}
+
+func (s *feedScanner) Scan() bool {
+	if s.raw == nil {
+		return false
+	}
+	s.p.raw = s.raw
+	s.raw = nil
+	return true
+}
+
+func (s *feedScanner) Bytes() []byte {
+	return s.line
+}
+
+func (s *feedScanner) Err() error {
+	return nil
+}
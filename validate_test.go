@@ -0,0 +1,83 @@
+package ihex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidatingWriter(t *testing.T) {
+	records := ":020000021200EA\r\n:02FFFF00000000\n" +
+		":0B0010006164647265737320676170A7\n:00000001FF"
+	var out bytes.Buffer
+	v := NewValidatingWriter(&out)
+	// write in small pieces to split lines between calls
+	for i := 0; i < len(records); i += 5 {
+		chunk := records[i:min(i+5, len(records))]
+		if n, err := v.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatal("unexpected error", n, err)
+		}
+	}
+	if err := v.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if out.String() != records {
+		t.Errorf("incorrect output %q", out.String())
+	}
+
+	out.Reset()
+	v = NewValidatingWriter(&out)
+	input := ":020000021200EA\n:00000001FE\n:00000001FF\n"
+	n, err := v.Write([]byte(input))
+	if n != 16 || err == nil ||
+		err.Error() != "line 2: invalid checksum: stored FE, computed FF" {
+		t.Error("missed invalid checksum", n, err)
+	}
+	if out.String() != ":020000021200EA\n" {
+		t.Errorf("incorrect output %q", out.String())
+	}
+	if _, err := v.Write([]byte("\n")); err == nil || v.Close() == nil {
+		t.Error("missed earlier error")
+	}
+
+	v = NewValidatingWriter(&out)
+	v.Write([]byte(":020000021200EA\n"))
+	if err := v.Close(); err == nil ||
+		err.Error() != "line 1: missing end record" {
+		t.Error("missed missing end record", err)
+	}
+
+	out.Reset()
+	v = NewValidatingWriter(&out, StopAtEnd())
+	input = ":00000001FF\nsignature\n"
+	if _, err := v.Write([]byte(input)); err != nil || v.Close() != nil {
+		t.Error("unexpected error", err)
+	}
+	if out.String() != input {
+		t.Errorf("incorrect output %q", out.String())
+	}
+
+	v = NewValidatingWriter(&out)
+	_, err = v.Write([]byte(strings.Repeat("0", 70000)))
+	if err == nil || err.Error() != "line 1: line too long" {
+		t.Error("missed long line", err)
+	}
+
+	out.Reset()
+	v = NewValidatingWriter(&out)
+	input = ":0400000001020304F2\r:00000001FF\r"
+	for i := range input {
+		if _, err := v.Write([]byte(input[i : i+1])); err != nil {
+			t.Fatal("unexpected error with CR line endings", err)
+		}
+	}
+	if err := v.Close(); err != nil || out.String() != input {
+		t.Errorf("incorrect output with CR line endings %q: %v", out.String(), err)
+	}
+
+	v = NewValidatingWriter(&out, MaxLineLength(20))
+	_, err = v.Write([]byte(":0B0010006164647265737320676170A7"))
+	if err == nil || err.Error() != "line 1: line too long" {
+		t.Error("missed line longer than MaxLineLength", err)
+	}
+}
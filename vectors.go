@@ -0,0 +1,119 @@
+package ihex
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// A VectorTable is a likely interrupt vector table found by
+// FindVectorTables.
+type VectorTable struct {
+	Arch    string // "arm" for Cortex-M, or "avr"
+	Address uint32
+	SP      uint32 // the initial stack pointer, for Cortex-M
+	Reset   uint32 // the address of the reset handler
+	Entries int    // the number of entries that look valid
+}
+
+// vectorAlign is the alignment at which FindVectorTables looks for a
+// table, which is the smallest that a Cortex-M vector table can have.
+const vectorAlign = 128
+
+// FindVectorTables looks for data in the Image that is laid out like
+// the vector table of a Cortex-M or AVR microcontroller, to help make
+// sense of a dump with no other information. It looks at the start of
+// each segment and at each multiple of 128 bytes. A Cortex-M table
+// starts with a word-aligned stack pointer and a reset vector pointing
+// to Thumb code within the Image, and is followed by more such vectors
+// or zeros; an AVR table is a run of at least four JMP or RJMP
+// instructions. The candidates are returned with those with the most
+// valid entries first, then in address order. They are only guesses.
+func FindVectorTables(img *Image) []VectorTable {
+	var found []VectorTable
+	for _, seg := range img.segs {
+		start := uint64(seg.Address)
+		end := start + uint64(len(seg.Bytes))
+		addr := start
+		for addr < end {
+			b := seg.Bytes[addr-start:]
+			if t, ok := armVectors(img, uint32(addr), b); ok {
+				found = append(found, t)
+			}
+			if t, ok := avrVectors(uint32(addr), b); ok {
+				found = append(found, t)
+			}
+			addr = (addr + vectorAlign) &^ (vectorAlign - 1)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Entries > found[j].Entries
+	})
+	return found
+}
+
+// armVectors checks for a Cortex-M vector table at addr, holding b.
+func armVectors(img *Image, addr uint32, b []byte) (VectorTable, bool) {
+	const numVectors = 16 // the system exceptions
+	if len(b) < 8 {
+		return VectorTable{}, false
+	}
+	sp := binary.LittleEndian.Uint32(b)
+	reset := binary.LittleEndian.Uint32(b[4:])
+	if sp == 0 || sp&3 != 0 || sp == 0xfffffffc || !isThumbCode(img, reset) {
+		return VectorTable{}, false
+	}
+	t := VectorTable{Arch: "arm", Address: addr, SP: sp, Reset: reset &^ 1}
+	for i := 1; i < numVectors && 4*i+4 <= len(b); i++ {
+		v := binary.LittleEndian.Uint32(b[4*i:])
+		if v != 0 && !isThumbCode(img, v) {
+			break
+		}
+		t.Entries++
+	}
+	return t, true
+}
+
+// isThumbCode reports whether v is the address of Thumb code within
+// the Image, with its low bit set.
+func isThumbCode(img *Image, v uint32) bool {
+	if v&1 == 0 {
+		return false
+	}
+	addr := uint64(v &^ 1)
+	return img.segs.slice(addr, addr+2) != nil
+}
+
+// avrVectors checks for an AVR vector table at addr, holding b.
+func avrVectors(addr uint32, b []byte) (VectorTable, bool) {
+	const minEntries = 4
+	t := VectorTable{Arch: "avr", Address: addr}
+	switch {
+	case len(b) >= 4 && isAVRJmp(b):
+		// JMP holds a 22-bit word address split across two words
+		hi := uint32(binary.LittleEndian.Uint16(b))
+		lo := uint32(binary.LittleEndian.Uint16(b[2:]))
+		t.Reset = 2 * ((hi&0x1f0)<<13 | (hi&1)<<16 | lo)
+		for ; len(b) >= 4 && isAVRJmp(b); b = b[4:] {
+			t.Entries++
+		}
+	case len(b) >= 2 && isAVRRjmp(b):
+		// RJMP holds a signed 12-bit word offset from the next word
+		k := int16(binary.LittleEndian.Uint16(b)<<4) >> 4
+		t.Reset = uint32(int64(addr) + 2 + 2*int64(k))
+		for ; len(b) >= 2 && isAVRRjmp(b); b = b[2:] {
+			t.Entries++
+		}
+	}
+	if t.Entries < minEntries {
+		return VectorTable{}, false
+	}
+	return t, true
+}
+
+func isAVRJmp(b []byte) bool {
+	return binary.LittleEndian.Uint16(b)&0xfe0e == 0x940c
+}
+
+func isAVRRjmp(b []byte) bool {
+	return binary.LittleEndian.Uint16(b)&0xf000 == 0xc000
+}
@@ -0,0 +1,35 @@
+package ihex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+func TestFindVectorTables(t *testing.T) {
+	var img Image
+	// a Cortex-M table with the reset, NMI and HardFault handlers
+	arm := binary.LittleEndian.AppendUint32(nil, 0x20005000)
+	for _, v := range []uint32{0x08000101, 0x08000105, 0x08000105} {
+		arm = binary.LittleEndian.AppendUint32(arm, v)
+	}
+	arm = append(arm, make([]byte, 0x100-len(arm))...)
+	arm = append(arm, 0x70, 0x47, 0x70, 0x47, 0xfe, 0xe7) // bx lr; b .
+	img.WriteAt(arm, 0x08000000)
+	// an AVR table of JMP 0x68 and one of RJMP .+30
+	img.WriteAt(bytes.Repeat([]byte{0x0c, 0x94, 0x34, 0x00}, 5), 0)
+	img.WriteAt(bytes.Repeat([]byte{0x0f, 0xc0}, 4), 0x1000)
+	// data that is not a table
+	img.WriteAt(bytes.Repeat([]byte{0xff}, 0x100), 0x2000)
+
+	got := FindVectorTables(&img)
+	want := []VectorTable{
+		{"arm", 0x08000000, 0x20005000, 0x08000100, 15},
+		{"avr", 0, 0, 0x68, 5},
+		{"avr", 0x1000, 0, 0x1020, 4},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
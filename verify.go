@@ -0,0 +1,83 @@
+package ihex
+
+import (
+	"bytes"
+	"io"
+)
+
+// A Mismatch is a run of bytes in which the data read by a Parser
+// differs from a reference.
+type Mismatch struct {
+	Address uint32
+	Got     []byte // the bytes read by the Parser
+	Want    []byte // the bytes from the reference
+	Err     error  // an error reading the reference
+}
+
+// Verify compares the data records read by p with ref, in which the byte
+// at offset n is the reference for address n, and returns each run of
+// bytes that differ. Bytes past the end of ref are compared with pad,
+// so a reference can be a flat binary trimmed of its padding. Only one
+// record at a time is held in memory.
+//
+// Verification stops at the first error. An error from p can be
+// accessed by its Err method; an error reading ref is reported by a
+// final Mismatch that has the address of the record being verified, no
+// data, and the Err field set.
+func Verify(p *Parser, ref io.ReaderAt, pad byte) []Mismatch {
+	var (
+		diffs []Mismatch
+		buf   [256]byte
+	)
+	for p.Parse() {
+		if !p.HasData() {
+			continue
+		}
+		data := p.Data()
+		want := buf[:len(data.Bytes)]
+		n, err := ref.ReadAt(want, int64(data.Address))
+		if err != nil && err != io.EOF {
+			return append(diffs, Mismatch{Address: data.Address, Err: err})
+		}
+		for i := n; i < len(want); i++ {
+			want[i] = pad
+		}
+		got := data.Bytes
+		addr := data.Address
+		for len(got) > 0 {
+			// skip the bytes that match, then take the run that doesn't
+			i := 0
+			for i < len(got) && got[i] == want[i] {
+				i++
+			}
+			j := i
+			for j < len(got) && got[j] != want[j] {
+				j++
+			}
+			if j > i {
+				diffs = addMismatch(diffs, addr+uint32(i), got[i:j], want[i:j])
+			}
+			got, want = got[j:], want[j:]
+			addr += uint32(j)
+		}
+	}
+	return diffs
+}
+
+// addMismatch appends copies of got and want to diffs, extending the
+// last Mismatch if the run follows on from it.
+func addMismatch(diffs []Mismatch, addr uint32, got, want []byte) []Mismatch {
+	if n := len(diffs); n > 0 {
+		last := &diffs[n-1]
+		if last.Address+uint32(len(last.Got)) == addr {
+			last.Got = append(last.Got, got...)
+			last.Want = append(last.Want, want...)
+			return diffs
+		}
+	}
+	return append(diffs, Mismatch{
+		Address: addr,
+		Got:     bytes.Clone(got),
+		Want:    bytes.Clone(want),
+	})
+}
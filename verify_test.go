@@ -0,0 +1,55 @@
+package ihex
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	records := `:0400000001020304F2
+:03000400050607E7
+:00000001FF
+`
+	ref := bytes.NewReader([]byte{1, 0xff, 0xff, 4, 5})
+	p := ParseString(records)
+	diffs := Verify(p, ref, 0)
+	if p.Err() != nil {
+		t.Fatal("unexpected error", p.Err())
+	}
+	want := []Mismatch{
+		{Address: 1, Got: []byte{2, 3}, Want: []byte{0xff, 0xff}},
+		{Address: 5, Got: []byte{6, 7}, Want: []byte{0, 0}},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d mismatches, got %d: %v", len(want), len(diffs), diffs)
+	}
+	for i := range diffs {
+		if diffs[i].Address != want[i].Address ||
+			!bytes.Equal(diffs[i].Got, want[i].Got) ||
+			!bytes.Equal(diffs[i].Want, want[i].Want) ||
+			diffs[i].Err != nil {
+			t.Errorf("mismatch %d: expected %v, got %v", i, want[i], diffs[i])
+		}
+	}
+
+	p = ParseString(records)
+	diffs = Verify(p, bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7}), 0)
+	if len(diffs) != 0 {
+		t.Errorf("expected no mismatches, got %v", diffs)
+	}
+}
+
+type errReaderAt struct{}
+
+func (errReaderAt) ReadAt([]byte, int64) (int, error) {
+	return 0, errors.New("readback failed")
+}
+
+func TestVerifyReadError(t *testing.T) {
+	p := ParseString(":03000400050607E7\n:00000001FF\n")
+	diffs := Verify(p, errReaderAt{}, 0)
+	if len(diffs) != 1 || diffs[0].Address != 4 || diffs[0].Err == nil {
+		t.Errorf("expected read error at 00000004, got %v", diffs)
+	}
+}
@@ -0,0 +1,50 @@
+package ihex
+
+import "fmt"
+
+// A ByteOrder is the order of the bytes of a word in a HEX file.
+type ByteOrder int
+
+const (
+	BigEndian    ByteOrder = iota // most significant byte first
+	LittleEndian                  // least significant byte first
+)
+
+// WordWidth makes the Parser treat data as words of n bytes, stored in
+// the file in the given byte order, and return each word with its most
+// significant byte first. It is an error for a data record to hold a
+// partial word, or to start at an address that is not a multiple of n.
+func WordWidth(n int, order ByteOrder) Option {
+	return func(p *Parser) {
+		p.wordWidth = n
+		p.wordOrder = order
+	}
+}
+
+// decodeWords checks that a data record holds whole words, and puts
+// their bytes in order.
+func (p *Parser) decodeWords(offset uint16, data []byte) {
+	n := p.wordWidth
+	if n <= 1 {
+		return
+	}
+	if len(data)%n != 0 {
+		p.err = p.makeError(fmt.Sprintf(
+			"record length %d is not a multiple of word width %d",
+			len(data), n))
+		return
+	}
+	if addr := p.address(offset); addr%uint32(n) != 0 {
+		p.err = p.makeError(fmt.Sprintf(
+			"data at %08X is not aligned to word width %d", addr, n))
+		return
+	}
+	if p.wordOrder == LittleEndian {
+		for i := 0; i < len(data); i += n {
+			word := data[i : i+n]
+			for j, k := 0, n-1; j < k; j, k = j+1, k-1 {
+				word[j], word[k] = word[k], word[j]
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ihex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWordWidth(t *testing.T) {
+	records := ":0400100034127856D8\n:00000001FF\n"
+	tests := []struct {
+		width int
+		order ByteOrder
+		want  []byte
+	}{
+		{2, LittleEndian, []byte{0x12, 0x34, 0x56, 0x78}},
+		{4, LittleEndian, []byte{0x56, 0x78, 0x12, 0x34}},
+		{2, BigEndian, []byte{0x34, 0x12, 0x78, 0x56}},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(records), WordWidth(tt.width, tt.order))
+		if !p.Parse() {
+			t.Fatal("unexpected error", p.Err())
+		}
+		if got := p.Data().Bytes; !bytes.Equal(got, tt.want) {
+			t.Errorf("width %d: expected % X, got % X", tt.width, tt.want, got)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{":030010003412782F\n:00000001FF\n",
+			"line 1: record length 3 is not a multiple of word width 2"},
+		{":020011003412A7\n:00000001FF\n",
+			"line 1: data at 00000011 is not aligned to word width 2"},
+	}
+	for _, tt := range errs {
+		p := NewParser(strings.NewReader(tt.input), WordWidth(2, LittleEndian))
+		for p.Parse() {
+		}
+		if p.Err() == nil || p.Err().Error() != tt.err {
+			t.Errorf("expected error %q, got %v", tt.err, p.Err())
+		}
+	}
+}
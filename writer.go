@@ -0,0 +1,277 @@
+package ihex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+var errClosed = errors.New("write after close")
+
+// A Writer writes records in Intel HEX format to an io.Writer. It emits
+// the extended address records needed for the data it is given, splits
+// data into records of a fixed maximum length, and computes checksums.
+// Output is buffered; Close writes the end record and flushes it.
+type Writer struct {
+	w         *bufio.Writer
+	reclen    int
+	segment   bool
+	base      uint32 // the start of the window selected by the last base record
+	digits    string
+	eol       string
+	forceBase bool
+	pending   *Image      // the data held for OptimizeLayout, or nil
+	held      []RawRecord // the start records held for OptimizeLayout
+	stable    bool
+	text      io.Writer // receives a copy of the output, if not nil
+	data      *dataDigest
+	trailer   func(sum []byte) []byte
+	hash      hash.Hash // the trailer checksum of the data written
+	buf       []byte
+	closed    bool
+	err       error
+	nrec      int
+	nbytes    int
+	start     time.Time // when the first record was written
+	elapsed   time.Duration
+}
+
+// A WriterOption configures a Writer.
+type WriterOption func(*Writer)
+
+// RecordLength sets the maximum number of data bytes in each data
+// record, which must be from 1 to 255; the default is 16.
+func RecordLength(n int) WriterOption {
+	return func(w *Writer) {
+		w.reclen = n
+	}
+}
+
+// SegmentAddressing makes the Writer emit extended segment address
+// records (type 2) instead of extended linear address records (type
+// 4), limiting addresses to the 1MB segmented address space.
+func SegmentAddressing() WriterOption {
+	return func(w *Writer) {
+		w.segment = true
+	}
+}
+
+// LowerCase makes the Writer use lowercase hexadecimal digits.
+func LowerCase() WriterOption {
+	return func(w *Writer) {
+		w.digits = lowerHexDigits
+	}
+}
+
+// CRLF makes the Writer end lines with a carriage return and line feed
+// instead of just a line feed.
+func CRLF() WriterOption {
+	return func(w *Writer) {
+		w.eol = "\r\n"
+	}
+}
+
+// AlwaysWriteBase makes the Writer write an extended address record
+// before the first data record, even if the data is in the first 64K
+// window, where one is not needed.
+func AlwaysWriteBase() WriterOption {
+	return func(w *Writer) {
+		w.forceBase = true
+	}
+}
+
+// Canonical resets any options given before it to the canonical form
+// written by WriteCanonical. It can be combined with later options, but
+// the output is then no longer canonical.
+func Canonical() WriterOption {
+	return func(w *Writer) {
+		w.reclen = 16
+		w.segment = false
+		w.digits = hexDigits
+		w.eol = "\n"
+		w.forceBase = false
+		w.pending = nil
+		w.stable = false
+	}
+}
+
+// NewWriter returns a new Writer that writes to w, configured by any
+// options given.
+func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
+	wr := &Writer{
+		w:      bufio.NewWriter(w),
+		reclen: 16,
+		digits: hexDigits,
+		eol:    "\n",
+	}
+	for _, opt := range opts {
+		opt(wr)
+	}
+	if wr.text != nil {
+		wr.w.Reset(io.MultiWriter(w, wr.text))
+	}
+	if wr.reclen < 1 || wr.reclen > 255 {
+		wr.err = fmt.Errorf("invalid record length %d", wr.reclen)
+	}
+	return wr
+}
+
+// WriteData writes data records holding b, starting at addr. Records
+// are split so that none crosses a 64K boundary, preceded by an
+// extended address record whenever the data moves to a different 64K
+// window. With OptimizeLayout, the data is instead held until Close.
+func (w *Writer) WriteData(addr uint32, b []byte) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	limit := uint64(1) << 32
+	if w.segment {
+		limit = 1 << 20
+	}
+	if uint64(addr)+uint64(len(b)) > limit {
+		w.err = fmt.Errorf("data at %08X-%08X outside of address space",
+			addr, uint64(addr)+uint64(len(b))-1)
+		return w.err
+	}
+	if w.pending != nil {
+		w.pending.WriteAt(b, int64(addr))
+		return nil
+	}
+	w.writeData(addr, b)
+	return w.err
+}
+
+// writeData writes the data records holding b, starting at addr, with
+// the extended address records they need.
+func (w *Writer) writeData(addr uint32, b []byte) {
+	if w.data != nil {
+		w.err = w.data.write(addr, b)
+	}
+	for len(b) > 0 && w.err == nil {
+		if addr < w.base || addr-w.base > 0xffff || w.forceBase {
+			w.writeBase(w.baseFor(addr))
+		}
+		n := min(len(b), w.reclen, 0x10000-int(addr-w.base))
+		if w.stable {
+			n = min(n, w.reclen-int(addr%uint32(w.reclen)))
+		}
+		w.record(0, uint16(addr-w.base), b[:n])
+		if w.hash != nil {
+			w.hash.Write(b[:n])
+		}
+		addr += uint32(n)
+		b = b[n:]
+	}
+}
+
+// WriteStart writes a start linear address record (type 5) holding eip.
+func (w *Writer) WriteStart(eip uint32) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.info(5, []byte{
+		byte(eip >> 24), byte(eip >> 16), byte(eip >> 8), byte(eip)})
+	return w.err
+}
+
+// WriteCSIP writes a start segment address record (type 3) holding cs
+// and ip.
+func (w *Writer) WriteCSIP(cs, ip uint16) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.info(3, []byte{
+		byte(cs >> 8), byte(cs), byte(ip >> 8), byte(ip)})
+	return w.err
+}
+
+// Close writes the end record and flushes any buffered output. It does
+// not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.flushLayout()
+	w.record(1, 0, nil)
+	w.writeTrailer()
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	w.closed = true
+	if !w.start.IsZero() {
+		w.elapsed = time.Since(w.start)
+	}
+	return w.err
+}
+
+func (w *Writer) check() error {
+	if w.closed {
+		return errClosed
+	}
+	return w.err
+}
+
+// info writes a record of type rectyp holding data, with a load offset
+// of zero, or holds it for OptimizeLayout.
+func (w *Writer) info(rectyp byte, data []byte) {
+	if w.pending != nil {
+		w.held = append(w.held, RawRecord{Type: rectyp, Data: data})
+		return
+	}
+	w.record(rectyp, 0, data)
+}
+
+// writeBase writes the extended address record that selects the 64K
+// window starting at base, which must be a multiple of 16, or of 64K
+// without SegmentAddressing.
+func (w *Writer) writeBase(base uint32) {
+	if w.segment {
+		seg := uint16(base >> 4)
+		w.record(2, 0, []byte{byte(seg >> 8), byte(seg)})
+	} else {
+		w.record(4, 0, []byte{byte(base >> 24), byte(base >> 16)})
+	}
+	w.base = base
+	w.forceBase = false
+}
+
+// record writes a single record with a computed checksum.
+func (w *Writer) record(rectyp byte, offset uint16, data []byte) {
+	if w.err != nil {
+		return
+	}
+	if w.start.IsZero() {
+		w.start = time.Now()
+	}
+	sum := byte(len(data)) + byte(offset>>8) + byte(offset) + rectyp
+	b := append(w.buf[:0], ':')
+	b = w.appendHexByte(b, byte(len(data)))
+	b = w.appendHexByte(b, byte(offset>>8))
+	b = w.appendHexByte(b, byte(offset))
+	b = w.appendHexByte(b, rectyp)
+	for _, d := range data {
+		b = w.appendHexByte(b, d)
+		sum += d
+	}
+	b = w.appendHexByte(b, -sum)
+	b = append(b, w.eol...)
+	w.buf = b
+	if _, w.err = w.w.Write(b); w.err == nil {
+		w.nrec++
+		if rectyp == 0 {
+			w.nbytes += len(data)
+		}
+	}
+}
+
+const (
+	hexDigits      = "0123456789ABCDEF"
+	lowerHexDigits = "0123456789abcdef"
+)
+
+func (w *Writer) appendHexByte(b []byte, v byte) []byte {
+	return append(b, w.digits[v>>4], w.digits[v&0xf])
+}
@@ -0,0 +1,91 @@
+package ihex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b)
+	if err := w.WriteData(0xfffe, []byte{1, 2, 3}); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := w.WriteStart(0x00010203); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `:02FFFE000102FE
+:020000040001F9
+:0100000003FC
+:0400000500010203F1
+:00000001FF
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+	if err := w.WriteData(0, []byte{1}); err != errClosed {
+		t.Errorf("expected %v, got %v", errClosed, err)
+	}
+}
+
+func TestWriterSegment(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b, SegmentAddressing())
+	w.WriteData(0x1ffff, []byte{1})
+	w.WriteCSIP(0x1234, 0x5678)
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `:020000021000EC
+:01FFFF000100
+:0400000312345678E5
+:00000001FF
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+
+	w = NewWriter(&b, SegmentAddressing())
+	if err := w.WriteData(0xfffff, []byte{1, 2}); err == nil {
+		t.Error("expected error for data outside of address space")
+	}
+}
+
+func TestWriterRecordLength(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b, RecordLength(2))
+	w.WriteData(0x12345, []byte{1, 2, 3, 4, 5})
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	recs, err := ParseRecords([]byte(b.String()))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	checkRecords(t, recs, []Record{
+		{0x12345, []byte{1, 2}},
+		{0x12347, []byte{3, 4}},
+		{0x12349, []byte{5}},
+	})
+
+	if err := NewWriter(&b, RecordLength(0)).Close(); err == nil {
+		t.Error("expected error for record length 0")
+	}
+}
+
+func TestWriterFormat(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b, LowerCase(), CRLF(), AlwaysWriteBase())
+	w.WriteData(0xfffe, []byte{1, 2, 3})
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := ":020000040000fa\r\n:02fffe000102fe\r\n" +
+		":020000040001f9\r\n:0100000003fc\r\n:00000001ff\r\n"
+	if b.String() != want {
+		t.Errorf("expected %q, got %q", want, b.String())
+	}
+}